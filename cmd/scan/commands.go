@@ -0,0 +1,1019 @@
+// Copyright 2022 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/julieqiu/github/internal/client"
+	"github.com/julieqiu/github/internal/colly"
+	"github.com/julieqiu/github/internal/reconcile"
+	"github.com/julieqiu/github/internal/report"
+	"github.com/julieqiu/github/internal/stats"
+	"github.com/julieqiu/github/internal/triage"
+	"github.com/julieqiu/github/internal/worker"
+	"golang.org/x/sync/errgroup"
+	vulnc "golang.org/x/vuln/client"
+	"golang.org/x/vuln/osv"
+)
+
+// A command is a subcommand of scan.
+type command struct {
+	// name is the command as typed, such as "ghsa get".
+	name string
+	// args describes the arguments after the flags, for the usage message.
+	args string
+	help string
+	run  func(ctx context.Context, c *command, args []string) error
+}
+
+var commands = []*command{
+	{name: "stats", help: "print statistics about issues and GHSAs", run: runStats},
+	{name: "issues list", help: "list vulndb issues", run: runIssuesList},
+	{name: "ghsa list", help: "list GHSAs that affect Go", run: runGHSAList},
+	{name: "ghsa get", args: "GHSA-ID", help: "print one GHSA", run: runGHSAGet},
+	{name: "ghsa file", help: "file tracking issues for GHSAs that have none", run: runGHSAFile},
+	{name: "ghsa for-cve", args: "CVE-ID", help: "list the GHSAs for a CVE", run: runGHSAForCVE},
+	{name: "relabel", args: "QUERY", help: "add, remove or set the labels of the issues matching a query", run: runRelabel},
+	{name: "lint", help: "check issues for inconsistent labels and state (exits 1 on problems)", run: runLint},
+	{name: "duplicates", help: "find issues that seem to be duplicates of each other (exits 1 if any are not labeled)", run: runDuplicates},
+	{name: "reconcile", help: "check that every GHSA has an issue (exits 1 if not)", run: runReconcile},
+	{name: "check-reports", help: "check that published reports agree with their GHSAs (exits 1 if not)", run: runCheckReports},
+	{name: "report draft", args: "ISSUE|GHSA-ID", help: "print a draft vulndb report made from the GHSA of an issue, or from a GHSA", run: runReportDraft},
+	{name: "releases", help: "list Go releases and their security fixes", run: runReleases},
+	{name: "export", help: "write a snapshot for cmd/web -snapshot", run: runExport},
+}
+
+// flagSet returns a FlagSet for the command whose usage message describes
+// the command.
+func (c *command) flagSet() *flag.FlagSet {
+	fs := flag.NewFlagSet(c.name, flag.ContinueOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "usage: scan %s [flags] %s\n\n%s.\n\nFlags:\n", c.name, c.args, c.help)
+		fs.PrintDefaults()
+	}
+	return fs
+}
+
+// parse parses the command's flags and checks that it has n arguments.
+func (c *command) parse(fs *flag.FlagSet, args []string, n int) error {
+	if err := fs.Parse(args); err != nil {
+		if err == flag.ErrHelp {
+			return err
+		}
+		return errUsage
+	}
+	if fs.NArg() != n {
+		fs.Usage()
+		return errUsage
+	}
+	return nil
+}
+
+// write prints v to stdout as indented JSON if asJSON is set, and otherwise
+// calls text to print it as a table.
+func write(asJSON bool, v any, text func(w io.Writer)) error {
+	if asJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(v)
+	}
+	tw := tabwriter.NewWriter(os.Stdout, 0, 8, 2, ' ', 0)
+	text(tw)
+	return tw.Flush()
+}
+
+func runStats(ctx context.Context, c *command, args []string) error {
+	fs := c.flagSet()
+	format := fs.String("format", "text", "output format: "+strings.Join(stats.Formats, ", "))
+	asJSON := fs.Bool("json", false, "print JSON (same as -format json)")
+	if err := c.parse(fs, args, 0); err != nil {
+		return err
+	}
+	if *asJSON {
+		*format = "json"
+	}
+	gc, err := newClient(ctx)
+	if err != nil {
+		return err
+	}
+	db, err := vulnc.NewClient([]string{*vulndbURL}, vulnc.Options{})
+	if err != nil {
+		return err
+	}
+	return stats.Stats(ctx, gc, db, os.Stdout, *format, labelNames)
+}
+
+func runIssuesList(ctx context.Context, c *command, args []string) error {
+	fs := c.flagSet()
+	state := fs.String("state", "all", "list only open, closed or all issues")
+	status := fs.String("status", "", "list only issues with this lifecycle status")
+	label := fs.String("label", "", "list only issues with this label")
+	module := fs.String("module", "", "list only issues for modules with this path prefix")
+	stdlib := fs.Bool("stdlib", false, "list only standard library issues")
+	thirdParty := fs.Bool("thirdparty", false, "list only third-party issues")
+	since := fs.String("since", "", "list only issues updated on or after this date (YYYY-MM-DD)")
+	malformed := fs.Bool("malformed", false, "list the issues that could not be parsed instead")
+	noReports := fs.Bool("noreports", false, "do not look up the published reports in the vulndb; the status is not shown, and HasReport is not set")
+	asJSON := fs.Bool("json", false, "print JSON")
+	if err := c.parse(fs, args, 0); err != nil {
+		return err
+	}
+	if *state != "all" && *state != "open" && *state != "closed" {
+		return fmt.Errorf("-state: want open, closed or all, got %q", *state)
+	}
+	if *status != "" {
+		if _, err := client.ParseStatus(*status); err != nil {
+			return fmt.Errorf("-status: %v", err)
+		}
+		if *noReports {
+			return errors.New("-status needs the published reports; it cannot be used with -noreports")
+		}
+	}
+	var sinceTime time.Time
+	if *since != "" {
+		t, err := time.Parse("2006-01-02", *since)
+		if err != nil {
+			return fmt.Errorf("-since: %v", err)
+		}
+		sinceTime = t
+	}
+	gc, err := newClient(ctx)
+	if err != nil {
+		return err
+	}
+	issues, bad, err := gc.ListByRepoSince(ctx, sinceTime)
+	if err != nil {
+		return err
+	}
+	wantState := func(open bool) bool {
+		return *state == "all" || (*state == "open") == open
+	}
+
+	if *malformed {
+		out := []*client.MalformedIssue{}
+		for _, m := range bad {
+			if wantState(m.Open) {
+				out = append(out, m)
+			}
+		}
+		sort.Slice(out, func(i, j int) bool { return out[i].Number > out[j].Number })
+		return write(*asJSON, out, func(w io.Writer) {
+			fmt.Fprintf(w, "NUMBER\tSTATE\tTITLE\tPROBLEM\n")
+			for _, m := range out {
+				fmt.Fprintf(w, "%d\t%s\t%s\t%s\n", m.Number, stateString(m.Open), m.Title, m.Reason)
+			}
+		})
+	}
+
+	// The status, shown in the table and used by -status, depends on
+	// HasReport.
+	if !*noReports {
+		if err := markReported(ctx, issues); err != nil {
+			return err
+		}
+	}
+	out := []*client.Issue{}
+	for _, i := range issues {
+		switch {
+		case !wantState(i.Open),
+			*status != "" && string(i.StatusUsing(labelNames)) != *status,
+			*label != "" && !i.Labels[*label],
+			*module != "" && !strings.HasPrefix(i.ModulePath, *module),
+			*stdlib && !i.IsStdLib,
+			*thirdParty && i.IsStdLib:
+			continue
+		}
+		out = append(out, i)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Number > out[j].Number })
+	return write(*asJSON, out, func(w io.Writer) {
+		fmt.Fprintf(w, "NUMBER\tSTATE\tSTATUS\tIDS\tMODULE\tLABELS\n")
+		for _, i := range out {
+			st := string(i.StatusUsing(labelNames))
+			if *noReports {
+				st = "-"
+			}
+			fmt.Fprintf(w, "%d\t%s\t%s\t%s\t%s\t%s\n", i.Number, stateString(i.Open), st,
+				strings.Join(i.Aliases, ","), i.ModulePath, strings.Join(sortedLabels(i), ","))
+		}
+	})
+}
+
+func runGHSAList(ctx context.Context, c *command, args []string) error {
+	fs := c.flagSet()
+	since := fs.String("since", "", "list only GHSAs updated on or after this date (YYYY-MM-DD)")
+	asJSON := fs.Bool("json", false, "print JSON")
+	if err := c.parse(fs, args, 0); err != nil {
+		return err
+	}
+	var sinceTime time.Time
+	if *since != "" {
+		t, err := time.Parse("2006-01-02", *since)
+		if err != nil {
+			return fmt.Errorf("-since: %v", err)
+		}
+		sinceTime = t
+	}
+	gc, err := newClient(ctx)
+	if err != nil {
+		return err
+	}
+	sas, err := gc.ListGHSAs(ctx, sinceTime)
+	if err != nil {
+		return err
+	}
+	sort.Slice(sas, func(i, j int) bool { return sas[i].PublishedAt.After(sas[j].PublishedAt) })
+	return writeGHSAs(*asJSON, sas)
+}
+
+func runGHSAGet(ctx context.Context, c *command, args []string) error {
+	fs := c.flagSet()
+	asJSON := fs.Bool("json", false, "print JSON")
+	if err := c.parse(fs, args, 1); err != nil {
+		return err
+	}
+	gc, err := newClient(ctx)
+	if err != nil {
+		return err
+	}
+	sa, err := gc.FetchGHSA(ctx, fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	return write(*asJSON, sa, func(w io.Writer) {
+		fmt.Fprintf(w, "ID\t%s\n", sa.PrettyID())
+		for _, id := range sa.Identifiers {
+			if id.Type != "GHSA" {
+				fmt.Fprintf(w, "%s\t%s\n", id.Type, id.Value)
+			}
+		}
+		fmt.Fprintf(w, "Summary\t%s\n", sa.Summary)
+		fmt.Fprintf(w, "Published\t%s\n", sa.PublishedAt.Format(time.RFC3339))
+		fmt.Fprintf(w, "Updated\t%s\n", sa.UpdatedAt.Format(time.RFC3339))
+		fmt.Fprintf(w, "URL\t%s\n", sa.Permalink)
+		for _, v := range sa.Vulns {
+			fmt.Fprintf(w, "Package\t%s %s (fixed in %s, %s)\n", v.Package, v.VulnerableVersionRange, orDash(v.EarliestFixedVersion), v.Severity)
+		}
+	})
+}
+
+func runGHSAForCVE(ctx context.Context, c *command, args []string) error {
+	fs := c.flagSet()
+	asJSON := fs.Bool("json", false, "print JSON")
+	if err := c.parse(fs, args, 1); err != nil {
+		return err
+	}
+	gc, err := newClient(ctx)
+	if err != nil {
+		return err
+	}
+	sas, err := gc.ListGHSAForCVE(ctx, fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	return writeGHSAs(*asJSON, sas)
+}
+
+func runGHSAFile(ctx context.Context, c *command, args []string) error {
+	fs := c.flagSet()
+	dryRun := fs.Bool("dry-run", false, "print the issues instead of filing them")
+	limit := fs.Int("limit", 0, "file at most this many issues (0 means no limit)")
+	asJSON := fs.Bool("json", false, "print JSON")
+	if err := c.parse(fs, args, 0); err != nil {
+		return err
+	}
+	gc, err := newClient(ctx)
+	if err != nil {
+		return err
+	}
+	db, err := vulnc.NewClient([]string{*vulndbURL}, vulnc.Options{})
+	if err != nil {
+		return err
+	}
+	ghsas, err := gc.ListGHSAs(ctx, time.Time{})
+	if err != nil {
+		return err
+	}
+	reports, err := listReports(ctx, db)
+	if err != nil {
+		return err
+	}
+	// List the issues last, to see as many recently filed ones as possible.
+	issues, malformed, err := gc.ListByRepo(ctx)
+	if err != nil {
+		return err
+	}
+	uncovered := reconcile.Reconcile(issues, ghsas, reports).Uncovered
+	if *limit > 0 && len(uncovered) > *limit {
+		uncovered = uncovered[:*limit]
+	}
+	filed, err := reconcile.FileIssues(ctx, gc, uncovered, malformed, *dryRun)
+	if filed == nil {
+		filed = []*reconcile.NewIssue{}
+	}
+	if werr := write(*asJSON, filed, func(w io.Writer) {
+		for _, ni := range filed {
+			if *dryRun {
+				fmt.Fprintf(w, "%s\n\n%s\n", ni.Title, ni.Body)
+				continue
+			}
+			fmt.Fprintf(w, "filed #%d\t%s\n", ni.Number, ni.Title)
+		}
+	}); werr != nil && err == nil {
+		err = werr
+	}
+	return err
+}
+
+func writeGHSAs(asJSON bool, sas []*client.SecurityAdvisory) error {
+	if sas == nil {
+		sas = []*client.SecurityAdvisory{}
+	}
+	return write(asJSON, sas, func(w io.Writer) {
+		fmt.Fprintf(w, "ID\tPUBLISHED\tPACKAGES\tSUMMARY\n")
+		for _, sa := range sas {
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", sa.PrettyID(), sa.PublishedAt.Format("2006-01-02"),
+				strings.Join(advisoryPackages(sa), ","), sa.Summary)
+		}
+	})
+}
+
+func runReconcile(ctx context.Context, c *command, args []string) error {
+	fs := c.flagSet()
+	asJSON := fs.Bool("json", false, "print JSON")
+	if err := c.parse(fs, args, 0); err != nil {
+		return err
+	}
+	gc, err := newClient(ctx)
+	if err != nil {
+		return err
+	}
+	db, err := vulnc.NewClient([]string{*vulndbURL}, vulnc.Options{})
+	if err != nil {
+		return err
+	}
+	issues, _, err := gc.ListByRepo(ctx)
+	if err != nil {
+		return err
+	}
+	ghsas, err := gc.ListGHSAs(ctx, time.Time{})
+	if err != nil {
+		return err
+	}
+	reports, err := listReports(ctx, db)
+	if err != nil {
+		return err
+	}
+	res := reconcile.Reconcile(issues, ghsas, reports)
+	reported := map[int]bool{}
+	for _, e := range reports {
+		if n, err := client.ReportIssueNumber(e.ID); err == nil {
+			reported[n] = true
+		}
+	}
+	for _, i := range issues {
+		i.HasReport = reported[i.Number]
+	}
+
+	type orphan struct {
+		Number int
+		Open   bool
+		Status client.Status
+		GHSAs  []string
+	}
+	out := struct {
+		Uncovered []*client.SecurityAdvisory
+		Orphaned  []orphan
+	}{Uncovered: []*client.SecurityAdvisory{}, Orphaned: []orphan{}}
+	out.Uncovered = append(out.Uncovered, res.Uncovered...)
+	for _, o := range res.Orphaned {
+		out.Orphaned = append(out.Orphaned, orphan{Number: o.Issue.Number, Open: o.Issue.Open, Status: o.Issue.StatusUsing(labelNames), GHSAs: o.GHSAs})
+	}
+	err = write(*asJSON, out, func(w io.Writer) {
+		fmt.Fprintf(w, "%d of %d GHSAs have no issue.\n", len(res.Uncovered), len(ghsas))
+		if len(res.Uncovered) > 0 {
+			fmt.Fprintf(w, "\nID\tPUBLISHED\tPACKAGES\tSUMMARY\n")
+			for _, sa := range res.Uncovered {
+				fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", sa.PrettyID(), sa.PublishedAt.Format("2006-01-02"),
+					strings.Join(advisoryPackages(sa), ","), sa.Summary)
+			}
+		}
+		fmt.Fprintf(w, "\n%d issues mention GHSAs that no longer exist.\n", len(res.Orphaned))
+		if len(res.Orphaned) > 0 {
+			fmt.Fprintf(w, "\nNUMBER\tSTATE\tSTATUS\tGHSAS\n")
+			for _, o := range res.Orphaned {
+				fmt.Fprintf(w, "%d\t%s\t%s\t%s\n", o.Issue.Number, stateString(o.Issue.Open), o.Issue.StatusUsing(labelNames), strings.Join(o.GHSAs, ","))
+			}
+		}
+	})
+	if err != nil {
+		return err
+	}
+	if res.HasGaps() {
+		return fmt.Errorf("%d uncovered GHSAs, %d issues with missing GHSAs", len(res.Uncovered), len(res.Orphaned))
+	}
+	return nil
+}
+
+func runLint(ctx context.Context, c *command, args []string) error {
+	fs := c.flagSet()
+	var ruleIDs listFlag
+	fs.Var(&ruleIDs, "rules", "comma-separated IDs of the rules to run (default all)")
+	failOn := fs.String("fail-on", "warning", "exit 1 if there are findings of this severity or worse")
+	list := fs.Bool("list", false, "list the rules and exit")
+	fix := fs.Bool("fix", false, "fix the findings whose rules have a fix")
+	var allow listFlag
+	fs.Var(&allow, "allow", "with -fix, comma-separated IDs of the rules whose fixes to apply (default all)")
+	dryRun := fs.Bool("dry-run", false, "with -fix, print the fixes without making them")
+	yes := fs.Bool("yes", false, "with -fix, make the fixes without asking for confirmation")
+	auditFile := fs.String("audit", "", "with -fix, append a JSON line for every change made to this file")
+	asJSON := fs.Bool("json", false, "print JSON")
+	if err := c.parse(fs, args, 0); err != nil {
+		return err
+	}
+	if *list {
+		return write(*asJSON, triage.Rules, func(w io.Writer) {
+			fmt.Fprintf(w, "RULE\tSEVERITY\tFIX\tDESCRIPTION\n")
+			for _, r := range triage.Rules {
+				fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", r.ID, r.Severity, yesNo(r.Fix != nil), r.Description)
+			}
+		})
+	}
+	if allow == nil {
+		allow = triage.FixableRules()
+	}
+	if _, err := triage.PlanFixes(nil, allow); err != nil {
+		return fmt.Errorf("-allow: %v", err)
+	}
+	threshold, err := triage.ParseSeverity(*failOn)
+	if err != nil {
+		return fmt.Errorf("-fail-on: %v", err)
+	}
+	rules, err := triage.SelectRules(ruleIDs)
+	if err != nil {
+		return err
+	}
+	gc, err := newClient(ctx)
+	if err != nil {
+		return err
+	}
+	issues, _, err := gc.ListByRepo(ctx)
+	if err != nil {
+		return err
+	}
+	if err := attachReports(ctx, issues); err != nil {
+		return err
+	}
+	findings := triage.Lint(issues, rules, labelNames)
+	if findings == nil {
+		findings = []*triage.Finding{}
+	}
+	if err := write(*asJSON, findings, func(w io.Writer) {
+		fmt.Fprintf(w, "ISSUE\tSEVERITY\tRULE\tMESSAGE\n")
+		for _, f := range findings {
+			fmt.Fprintf(w, "%d\t%s\t%s\t%s\n", f.Number, f.Severity, f.Rule, f.Message)
+		}
+	}); err != nil {
+		return err
+	}
+	fixed := map[*triage.Finding]bool{}
+	if *fix {
+		plan, err := triage.PlanFixes(findings, allow)
+		if err != nil {
+			return err
+		}
+		if fixed, err = applyFixes(ctx, gc, plan, *dryRun, *yes, *auditFile); err != nil {
+			return err
+		}
+	}
+	failed := 0
+	for _, f := range findings {
+		if f.Severity >= threshold && !fixed[f] {
+			failed++
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d findings of severity %s or worse", failed, threshold)
+	}
+	return nil
+}
+
+func runDuplicates(ctx context.Context, c *command, args []string) error {
+	fs := c.flagSet()
+	fix := fs.Bool("fix", false, "label each duplicate and comment on it with a link to the canonical issue")
+	dryRun := fs.Bool("dry-run", false, "with -fix, print the changes without making them")
+	yes := fs.Bool("yes", false, "with -fix, make the changes without asking for confirmation")
+	auditFile := fs.String("audit", "", "with -fix, append a JSON line for every change made to this file")
+	asJSON := fs.Bool("json", false, "print JSON")
+	if err := c.parse(fs, args, 0); err != nil {
+		return err
+	}
+	gc, err := newClient(ctx)
+	if err != nil {
+		return err
+	}
+	issues, _, err := gc.ListByRepo(ctx)
+	if err != nil {
+		return err
+	}
+	if err := attachReports(ctx, issues); err != nil {
+		return err
+	}
+	sas, err := gc.ListGHSAs(ctx, time.Time{})
+	if err != nil {
+		return err
+	}
+	groups := triage.FindDuplicates(issues, sas, labelNames)
+	if groups == nil {
+		groups = []*triage.DuplicateGroup{}
+	}
+	if err := write(*asJSON, groups, func(w io.Writer) {
+		fmt.Fprintf(w, "ISSUE\tDUPLICATES\tKIND\tWHY\n")
+		for _, g := range groups {
+			var dups []string
+			for _, d := range g.Duplicates {
+				dups = append(dups, fmt.Sprint(d.Number))
+			}
+			kind := "duplicate"
+			if g.Overlapping {
+				kind = "overlapping"
+			}
+			fmt.Fprintf(w, "%d\t%s\t%s\t%s\n", g.Canonical.Number, strings.Join(dups, ","), kind, strings.Join(g.Reasons, "; "))
+		}
+	}); err != nil {
+		return err
+	}
+	// Overlapping groups are only hints, with no fix.
+	var findings []*triage.Finding
+	for _, f := range triage.DuplicateFindings(groups, labelNames) {
+		if f.Fix != nil {
+			findings = append(findings, f)
+		}
+	}
+	fixed := map[*triage.Finding]bool{}
+	if *fix {
+		if fixed, err = applyFixes(ctx, gc, findings, *dryRun, *yes, *auditFile); err != nil {
+			return err
+		}
+	}
+	if n := len(findings) - len(fixed); n > 0 {
+		return fmt.Errorf("%d suspected duplicates are not labeled %s", n, labelNames.Duplicate)
+	}
+	return nil
+}
+
+// applyFixes prints the fixes in plan to stderr and, unless dryRun is set,
+// asks for confirmation and applies them, logging every change to the file
+// auditFile if it is not empty. It returns the findings that were fixed.
+func applyFixes(ctx context.Context, gc *client.Client, plan []*triage.Finding, dryRun, yes bool, auditFile string) (map[*triage.Finding]bool, error) {
+	if len(plan) == 0 {
+		fmt.Fprintln(os.Stderr, "nothing to fix")
+		return nil, nil
+	}
+	fmt.Fprintf(os.Stderr, "\nFixes:\n")
+	for _, f := range plan {
+		fmt.Fprintf(os.Stderr, "  #%d: %s [%s]\n", f.Number, f.Fix, f.Rule)
+	}
+	if dryRun {
+		return nil, nil
+	}
+	if !yes && !confirm(fmt.Sprintf("Apply %d fixes?", len(plan))) {
+		fmt.Fprintln(os.Stderr, "no changes made")
+		return nil, nil
+	}
+	var audit io.Writer
+	if auditFile != "" {
+		f, err := os.OpenFile(auditFile, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0644)
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+		audit = f
+	}
+	n, err := triage.ApplyFixes(ctx, gc, plan, audit)
+	fixed := map[*triage.Finding]bool{}
+	for _, f := range plan[:n] {
+		fixed[f] = true
+	}
+	fmt.Fprintf(os.Stderr, "fixed %d of %d findings\n", n, len(plan))
+	return fixed, err
+}
+
+func runRelabel(ctx context.Context, c *command, args []string) error {
+	fs := c.flagSet()
+	var edit triage.LabelEdit
+	fs.Var((*listFlag)(&edit.Add), "add", "comma-separated labels to add")
+	fs.Var((*listFlag)(&edit.Remove), "remove", "comma-separated labels to remove")
+	fs.Var((*listFlag)(&edit.Set), "set", "comma-separated labels to replace all labels with")
+	dryRun := fs.Bool("dry-run", false, "print the changes without making them")
+	yes := fs.Bool("yes", false, "make the changes without asking for confirmation")
+	asJSON := fs.Bool("json", false, "print the changes as JSON")
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "usage: scan relabel [flags] QUERY\n\n"+
+			"Change the labels of the issues matching QUERY, for example\n\n"+
+			"\tscan relabel -add stdlib is:stdlib -label:stdlib\n\n"+
+			"Query terms are is:open, is:closed, is:stdlib, is:thirdparty, is:reported,\n"+
+			"status:NAME, label:NAME, module:PREFIX and number:N; prefix a term with -\n"+
+			"to negate it.\n\nFlags:\n")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		if err == flag.ErrHelp {
+			return err
+		}
+		return errUsage
+	}
+	if fs.NArg() == 0 || (edit.Add == nil && edit.Remove == nil && edit.Set == nil) {
+		fs.Usage()
+		return errUsage
+	}
+	q, err := triage.ParseQuery(strings.Join(fs.Args(), " "), labelNames)
+	if err != nil {
+		return err
+	}
+	gc, err := newClient(ctx)
+	if err != nil {
+		return err
+	}
+	issues, _, err := gc.ListByRepo(ctx)
+	if err != nil {
+		return err
+	}
+	if strings.Contains(q.String(), "is:reported") || strings.Contains(q.String(), "status:") {
+		if err := attachReports(ctx, issues); err != nil {
+			return err
+		}
+	}
+	changes, err := triage.PlanRelabel(issues, q, edit)
+	if err != nil {
+		return err
+	}
+	if changes == nil {
+		changes = []*triage.LabelChange{}
+	}
+	if err := write(*asJSON, changes, func(w io.Writer) {
+		for _, c := range changes {
+			fmt.Fprint(w, c)
+		}
+	}); err != nil {
+		return err
+	}
+	if len(changes) == 0 {
+		fmt.Fprintln(os.Stderr, "no issues need changing")
+		return nil
+	}
+	if *dryRun {
+		return nil
+	}
+	if !*yes && !confirm(fmt.Sprintf("Change the labels of %d issues?", len(changes))) {
+		fmt.Fprintln(os.Stderr, "no changes made")
+		return nil
+	}
+	n, err := triage.ApplyLabelChanges(ctx, gc, changes)
+	fmt.Fprintf(os.Stderr, "changed the labels of %d issues\n", n)
+	return err
+}
+
+// attachReports sets the HasReport and OSV fields of the issues that have a
+// vulndb report.
+func attachReports(ctx context.Context, issues []*client.Issue) error {
+	db, err := vulnc.NewClient([]string{*vulndbURL}, vulnc.Options{})
+	if err != nil {
+		return err
+	}
+	reports, err := listReports(ctx, db)
+	if err != nil {
+		return err
+	}
+	byNumber := map[int]*osv.Entry{}
+	for _, e := range reports {
+		if n, err := client.ReportIssueNumber(e.ID); err == nil {
+			byNumber[n] = e
+		}
+	}
+	for _, i := range issues {
+		i.SetReport(byNumber[i.Number])
+	}
+	return nil
+}
+
+// markReported sets the HasReport field of each issue. Unlike attachReports,
+// it only lists the IDs in the vulndb, and does not set the OSV field.
+func markReported(ctx context.Context, issues []*client.Issue) error {
+	db, err := vulnc.NewClient([]string{*vulndbURL}, vulnc.Options{})
+	if err != nil {
+		return err
+	}
+	ids, err := db.ListIDs(ctx)
+	if err != nil {
+		return err
+	}
+	reported := map[int]bool{}
+	for _, id := range ids {
+		if n, err := client.ReportIssueNumber(id); err == nil {
+			reported[n] = true
+		}
+	}
+	for _, i := range issues {
+		i.HasReport = reported[i.Number]
+	}
+	return nil
+}
+
+// confirm asks a yes-or-no question on stderr and reports whether the answer
+// read from stdin was yes.
+func confirm(question string) bool {
+	fmt.Fprintf(os.Stderr, "%s [y/N] ", question)
+	var answer string
+	fmt.Scanln(&answer)
+	answer = strings.ToLower(strings.TrimSpace(answer))
+	return answer == "y" || answer == "yes"
+}
+
+// A listFlag is a flag holding a comma-separated list. It is nil until the
+// flag is set, so that an empty relabel -set can remove every label.
+type listFlag []string
+
+func (l *listFlag) String() string {
+	if l == nil {
+		return ""
+	}
+	return strings.Join(*l, ",")
+}
+
+func (l *listFlag) Set(s string) error {
+	*l = []string{}
+	for _, f := range strings.Split(s, ",") {
+		if f = strings.TrimSpace(f); f != "" {
+			*l = append(*l, f)
+		}
+	}
+	return nil
+}
+
+func yesNo(b bool) string {
+	if b {
+		return "yes"
+	}
+	return "no"
+}
+
+func runCheckReports(ctx context.Context, c *command, args []string) error {
+	fs := c.flagSet()
+	asJSON := fs.Bool("json", false, "print JSON")
+	if err := c.parse(fs, args, 0); err != nil {
+		return err
+	}
+	gc, err := newClient(ctx)
+	if err != nil {
+		return err
+	}
+	issues, _, err := gc.ListByRepo(ctx)
+	if err != nil {
+		return err
+	}
+	if err := attachReports(ctx, issues); err != nil {
+		return err
+	}
+	ms, err := reconcile.CheckReports(ctx, gc, issues, nil)
+	if err != nil {
+		return err
+	}
+	if ms == nil {
+		ms = []*reconcile.Mismatch{}
+	}
+	if err := write(*asJSON, ms, func(w io.Writer) {
+		fmt.Fprintf(w, "ISSUE\tREPORT\tGHSA\tKIND\tPACKAGE\tMESSAGE\n")
+		for _, m := range ms {
+			fmt.Fprintf(w, "%d\t%s\t%s\t%s\t%s\t%s\n", m.Issue, m.Report, m.GHSA, m.Kind, m.Package, m.Message)
+		}
+	}); err != nil {
+		return err
+	}
+	if len(ms) > 0 {
+		return fmt.Errorf("%d mismatches between reports and GHSAs", len(ms))
+	}
+	return nil
+}
+
+func runReportDraft(ctx context.Context, c *command, args []string) error {
+	fs := c.flagSet()
+	modulePath := fs.String("module", "", "module of the vulnerability (default: the module of the issue)")
+	output := fs.String("o", "", "write to this file instead of stdout")
+	if err := c.parse(fs, args, 1); err != nil {
+		return err
+	}
+	gc, err := newClient(ctx)
+	if err != nil {
+		return err
+	}
+	ghsaID := fs.Arg(0)
+	if n, err := strconv.Atoi(strings.TrimPrefix(ghsaID, "#")); err == nil {
+		i, err := findIssue(ctx, gc, n)
+		if err != nil {
+			return err
+		}
+		if *modulePath == "" {
+			*modulePath = i.ModulePath
+		}
+		if ghsaID, err = issueGHSA(ctx, gc, i); err != nil {
+			return err
+		}
+	}
+	sa, err := gc.FetchGHSA(ctx, ghsaID)
+	if err != nil {
+		return err
+	}
+	var buf bytes.Buffer
+	if err := report.FromGHSA(sa, *modulePath).WriteYAML(&buf); err != nil {
+		return err
+	}
+	if *output == "" {
+		_, err = os.Stdout.Write(buf.Bytes())
+		return err
+	}
+	return os.WriteFile(*output, buf.Bytes(), 0644)
+}
+
+// findIssue returns the vulndb issue with the given number.
+func findIssue(ctx context.Context, gc *client.Client, number int) (*client.Issue, error) {
+	issues, _, err := gc.ListByRepo(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, i := range issues {
+		if i.Number == number {
+			return i, nil
+		}
+	}
+	return nil, fmt.Errorf("no vulndb issue #%d", number)
+}
+
+// issueGHSA returns the ID of the GHSA of the issue: the first one in its
+// title, or else the first GHSA for its CVE.
+func issueGHSA(ctx context.Context, gc *client.Client, i *client.Issue) (string, error) {
+	if i.GHSA != "" {
+		return i.GHSA, nil
+	}
+	if i.CVE != "" {
+		sas, err := gc.ListGHSAForCVE(ctx, i.CVE)
+		if err != nil {
+			return "", err
+		}
+		if len(sas) > 0 {
+			return sas[0].PrettyID(), nil
+		}
+	}
+	return "", fmt.Errorf("issue #%d has no GHSA", i.Number)
+}
+
+func runReleases(ctx context.Context, c *command, args []string) error {
+	fs := c.flagSet()
+	security := fs.Bool("security", false, "list only releases with security fixes")
+	asJSON := fs.Bool("json", false, "print JSON")
+	if err := c.parse(fs, args, 0); err != nil {
+		return err
+	}
+	notes, err := colly.New().ReleaseNotes()
+	if err != nil {
+		return err
+	}
+	out := []*colly.ReleaseNote{}
+	for _, n := range notes {
+		if !*security || n.Description != "" {
+			out = append(out, n)
+		}
+	}
+	return write(*asJSON, out, func(w io.Writer) {
+		fmt.Fprintf(w, "VERSION\tSECURITY FIXES\n")
+		for _, n := range out {
+			fmt.Fprintf(w, "%s\t%s\n", n.Version, n.Description)
+		}
+	})
+}
+
+func runExport(ctx context.Context, c *command, args []string) error {
+	fs := c.flagSet()
+	output := fs.String("o", "", "write to this file instead of stdout")
+	if err := c.parse(fs, args, 0); err != nil {
+		return err
+	}
+	gc, err := newClient(ctx)
+	if err != nil {
+		return err
+	}
+	db, err := vulnc.NewClient([]string{*vulndbURL}, vulnc.Options{})
+	if err != nil {
+		return err
+	}
+	m, err := export(ctx, gc, db)
+	if err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return err
+	}
+	data = append(data, '\n')
+	if *output == "" {
+		_, err = os.Stdout.Write(data)
+		return err
+	}
+	return os.WriteFile(*output, data, 0644)
+}
+
+// export fetches everything shown by the dashboard.
+func export(ctx context.Context, gc *client.Client, db vulnc.Client) (_ *worker.MemorySource, err error) {
+	m := &worker.MemorySource{}
+	if m.Issues, m.Malformed, err = gc.ListByRepo(ctx); err != nil {
+		return nil, err
+	}
+	if m.GHSAs, err = gc.ListGHSAs(ctx, time.Time{}); err != nil {
+		return nil, err
+	}
+	if m.ReleaseNotes, err = colly.New().ReleaseNotes(); err != nil {
+		return nil, err
+	}
+	if m.Reports, err = listReports(ctx, db); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// listReports returns all the reports in the vulndb.
+func listReports(ctx context.Context, db vulnc.Client) ([]*osv.Entry, error) {
+	ids, err := db.ListIDs(ctx)
+	if err != nil {
+		return nil, err
+	}
+	all := make([]*osv.Entry, len(ids))
+	g, ctx := errgroup.WithContext(ctx)
+	g.SetLimit(10)
+	for k, id := range ids {
+		k, id := k, id
+		g.Go(func() error {
+			e, err := db.GetByID(ctx, id)
+			if err != nil {
+				return err
+			}
+			all[k] = e
+			return nil
+		})
+	}
+	if err := g.Wait(); err != nil {
+		return nil, err
+	}
+	var entries []*osv.Entry
+	for _, e := range all {
+		if e != nil {
+			entries = append(entries, e)
+		}
+	}
+	return entries, nil
+}
+
+func advisoryPackages(sa *client.SecurityAdvisory) []string {
+	var pkgs []string
+	for _, v := range sa.Vulns {
+		pkgs = append(pkgs, v.Package)
+	}
+	return pkgs
+}
+
+func stateString(open bool) string {
+	if open {
+		return "open"
+	}
+	return "closed"
+}
+
+func sortedLabels(i *client.Issue) []string {
+	var ls []string
+	for l := range i.Labels {
+		ls = append(ls, l)
+	}
+	sort.Strings(ls)
+	return ls
+}
+
+func orDash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
@@ -1,13 +1,30 @@
+// Copyright 2022 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Command scan reports on the vulndb issues, the GitHub security advisories
+// that affect Go, and the Go security releases.
+//
+// Usage:
+//
+//	scan [flags] command [command flags] [args]
+//
+// Run "scan help" for the list of commands. Every command that prints data
+// accepts -json, to print it as JSON instead of a table, except export,
+// which always writes JSON.
 package main
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"flag"
+	"fmt"
 	"log"
+	"os"
+	"strings"
 
 	"github.com/julieqiu/github/internal/client"
-	"github.com/julieqiu/github/internal/colly"
-	"github.com/julieqiu/github/internal/stats"
 )
 
 const (
@@ -15,21 +32,115 @@ const (
 	repoName = "vulndb"
 )
 
-var tok = flag.String("tok", "", "GitHub access token")
+var (
+	tok = flag.String("tok", os.Getenv("GITHUB_TOKEN"), "GitHub access token (default $GITHUB_TOKEN)")
+
+	restURL    = flag.String("rest-url", "", "base URL of the GitHub REST API (default https://api.github.com/)")
+	graphQLURL = flag.String("graphql-url", "", "URL of the GitHub GraphQL API (default https://api.github.com/graphql)")
+	userAgent  = flag.String("user-agent", "", "User-Agent header to send to GitHub")
+	vulndbURL  = flag.String("vulndb", "https://vuln.go.dev", "URL of the vulndb; file:// URLs are allowed")
+
+	// labelNames are the names of the triage labels, set by -labels.
+	labelNames = client.DefaultLabelNames
+)
+
+func init() {
+	flag.Var((*labelNamesFlag)(&labelNames), "labels", `JSON object renaming the triage labels, such as {"NotGoVuln": "not-go"} (default the golang/vulndb names)`)
+}
+
+// errUsage is returned by commands whose arguments are wrong, after
+// printing their usage.
+var errUsage = errors.New("usage")
 
 func main() {
 	ctx := context.Background()
+	flag.Usage = usage
 	flag.Parse()
-	if *tok == "" {
-		log.Fatalf("no token")
+	if flag.NArg() == 0 {
+		usage()
+		os.Exit(2)
 	}
-	if err := run(ctx, repoName, *tok); err != nil {
+	err := run(ctx, flag.Args())
+	if errors.Is(err, flag.ErrHelp) {
+		return
+	}
+	if errors.Is(err, errUsage) {
+		os.Exit(2)
+	}
+	if err != nil {
 		log.Fatal(err)
 	}
 }
 
-func run(ctx context.Context, repo, tok string) error {
-	client := client.New(owner, repoName, tok)
-	col := colly.New()
-	return stats.Stats(ctx, client, col)
+// run runs the command named by the first one or two arguments.
+func run(ctx context.Context, args []string) error {
+	for _, c := range commands {
+		words := strings.Fields(c.name)
+		if len(args) >= len(words) && strings.Join(args[:len(words)], " ") == c.name {
+			return c.run(ctx, c, args[len(words):])
+		}
+	}
+	if args[0] == "help" {
+		usage()
+		return nil
+	}
+	fmt.Fprintf(os.Stderr, "scan: unknown command %q\n", strings.Join(args, " "))
+	usage()
+	return errUsage
+}
+
+func usage() {
+	out := flag.CommandLine.Output()
+	fmt.Fprintf(out, "usage: scan [flags] command [command flags] [args]\n\nCommands:\n")
+	for _, c := range commands {
+		fmt.Fprintf(out, "  %-14s %s\n", c.name, c.help)
+	}
+	fmt.Fprintf(out, "\nFlags:\n")
+	flag.PrintDefaults()
+}
+
+// newClient returns a GitHub client for the vulndb repo.
+func newClient(ctx context.Context) (*client.Client, error) {
+	if *tok == "" {
+		return nil, errors.New("no token: set -tok or $GITHUB_TOKEN")
+	}
+	return client.New(ctx, owner, repoName, *tok, clientOptions()...)
+}
+
+// clientOptions returns the GitHub client options set by flags.
+func clientOptions() []client.Option {
+	opts := []client.Option{client.WithLabelNames(labelNames)}
+	if *restURL != "" {
+		opts = append(opts, client.WithRESTURL(*restURL))
+	}
+	if *graphQLURL != "" {
+		opts = append(opts, client.WithGraphQLURL(*graphQLURL))
+	}
+	if *userAgent != "" {
+		opts = append(opts, client.WithUserAgent(*userAgent))
+	}
+	return opts
+}
+
+// A labelNamesFlag is a flag holding client.LabelNames as a JSON object.
+// Names that are not given keep their defaults.
+type labelNamesFlag client.LabelNames
+
+func (f *labelNamesFlag) String() string {
+	if f == nil {
+		return ""
+	}
+	data, _ := json.Marshal(f)
+	return string(data)
+}
+
+func (f *labelNamesFlag) Set(s string) error {
+	var names client.LabelNames
+	dec := json.NewDecoder(strings.NewReader(s))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&names); err != nil {
+		return err
+	}
+	*f = labelNamesFlag(names.WithDefaults())
+	return nil
 }
@@ -5,10 +5,12 @@ import (
 	"flag"
 	"fmt"
 	"net/http"
+	"time"
 
 	log "github.com/julieqiu/dlog"
 	"github.com/julieqiu/github/internal/client"
 	"github.com/julieqiu/github/internal/colly"
+	"github.com/julieqiu/github/internal/store"
 	"github.com/julieqiu/github/internal/worker"
 	vulnc "golang.org/x/vuln/client"
 )
@@ -18,12 +20,22 @@ const (
 	repoName = "vulndb"
 )
 
-var tok = flag.String("tok", "", "GitHub access token")
+var (
+	tok       = flag.String("tok", "", "GitHub access token")
+	storeFile = flag.String("store", "vulndb-store.json", "file in which to persist synced data")
+	refresh   = flag.Duration("refresh", 15*time.Minute, "interval between background refreshes; must be positive")
+	snapshot  = flag.String("snapshot", "", "serve the dashboard from this JSON file instead of GitHub and the vulndb")
+	rules     = flag.String("rules", "", "JSON file of rules for sorting issues into dashboard sections (default one section per status)")
+
+	restURL    = flag.String("rest-url", "", "base URL of the GitHub REST API (default https://api.github.com/)")
+	graphQLURL = flag.String("graphql-url", "", "URL of the GitHub GraphQL API (default https://api.github.com/graphql)")
+	userAgent  = flag.String("user-agent", "", "User-Agent header to send to GitHub")
+)
 
 func main() {
 	ctx := context.Background()
 	flag.Parse()
-	if *tok == "" {
+	if *tok == "" && *snapshot == "" {
 		log.Fatalf(ctx, "no token")
 	}
 	if err := run(ctx, repoName, *tok); err != nil {
@@ -32,17 +44,71 @@ func main() {
 }
 
 func run(ctx context.Context, repoName, tok string) error {
-	githubClient := client.New(ctx, owner, repoName, tok)
-	dbs := []string{"https://vuln.go.dev"}
-	dbClient, err := vulnc.NewClient(dbs, vulnc.Options{})
+	r := worker.DefaultRules()
+	if *rules != "" {
+		var err error
+		if r, err = worker.ReadRules(*rules); err != nil {
+			return err
+		}
+	}
+	sources, err := newSources(ctx, repoName, tok, r.Labels)
 	if err != nil {
 		return err
 	}
-	collyClient := colly.New()
-	if _, err := worker.NewServer(ctx, githubClient, dbClient, collyClient); err != nil {
+	if _, err := worker.NewServer(ctx, sources, r, *refresh); err != nil {
 		return err
 	}
 	addr := ":6060"
 	log.Infof(ctx, "Listening on addr http://localhost%s", addr)
 	return fmt.Errorf("listening: %v", http.ListenAndServe(addr, nil))
 }
+
+// newSources returns the sources of dashboard data: a store synced from
+// GitHub and the vulndb, or the snapshot file if one was given. The triage
+// labels have the given names.
+func newSources(ctx context.Context, repoName, tok string, labels client.LabelNames) (worker.Sources, error) {
+	if *snapshot != "" {
+		m, err := worker.ReadMemorySource(*snapshot)
+		if err != nil {
+			return worker.Sources{}, err
+		}
+		return m.Sources(), nil
+	}
+	opts := append(clientOptions(), client.WithLabelNames(labels))
+	githubClient, err := client.New(ctx, owner, repoName, tok, opts...)
+	if err != nil {
+		return worker.Sources{}, err
+	}
+	dbs := []string{"https://vuln.go.dev"}
+	dbClient, err := vulnc.NewClient(dbs, vulnc.Options{})
+	if err != nil {
+		return worker.Sources{}, err
+	}
+	st, err := store.Open(*storeFile)
+	if err != nil {
+		return worker.Sources{}, err
+	}
+	src := st.Source(githubClient, githubClient, dbClient)
+	return worker.Sources{
+		Issues:       src,
+		Advisories:   src,
+		Reports:      src,
+		ReleaseNotes: colly.New(),
+		RateLimits:   githubClient,
+	}, nil
+}
+
+// clientOptions returns the GitHub client options set by flags.
+func clientOptions() []client.Option {
+	var opts []client.Option
+	if *restURL != "" {
+		opts = append(opts, client.WithRESTURL(*restURL))
+	}
+	if *graphQLURL != "" {
+		opts = append(opts, client.WithGraphQLURL(*graphQLURL))
+	}
+	if *userAgent != "" {
+		opts = append(opts, client.WithUserAgent(*userAgent))
+	}
+	return opts
+}
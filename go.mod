@@ -5,6 +5,7 @@ go 1.18
 require (
 	github.com/gocolly/colly/v2 v2.1.0
 	github.com/google/go-github/v41 v41.0.0
+	github.com/google/go-querystring v1.1.0
 	github.com/google/safehtml v0.0.2
 	github.com/julieqiu/derrors v0.0.0-20210614022941-f601489ffd41
 	github.com/julieqiu/dlog v0.0.0-20220521223154-5020f471fd21
@@ -28,7 +29,6 @@ require (
 	github.com/golang/groupcache v0.0.0-20200121045136-8c9f03a8e57e // indirect
 	github.com/golang/protobuf v1.5.2 // indirect
 	github.com/google/go-cmp v0.5.8 // indirect
-	github.com/google/go-querystring v1.1.0 // indirect
 	github.com/googleapis/enterprise-certificate-proxy v0.0.0-20220520183353-fd19c99a87aa // indirect
 	github.com/googleapis/gax-go/v2 v2.4.0 // indirect
 	github.com/kennygrant/sanitize v1.2.4 // indirect
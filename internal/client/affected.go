@@ -0,0 +1,160 @@
+// Copyright 2022 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package client
+
+import (
+	"strings"
+
+	"golang.org/x/vuln/osv"
+)
+
+// An Affected is a package affected by a vulnerability, as described by the
+// issue's vulndb report.
+type Affected struct {
+	// Module is the path of the module that contains Package, if known:
+	// "std" for the standard library, or the issue's module path if it is a
+	// prefix of Package.
+	Module    string `json:",omitempty"`
+	Package   string
+	Ecosystem string
+	// Ranges are the vulnerable version ranges, in report order.
+	Ranges []*VersionRange
+	// Symbols, GOOS and GOARCH are the ecosystem-specific data of the
+	// report. Empty lists mean all symbols, operating systems and
+	// architectures.
+	Symbols []string `json:",omitempty"`
+	GOOS    []string `json:",omitempty"`
+	GOARCH  []string `json:",omitempty"`
+	// URL is the page for the vulnerability in the database, if any.
+	URL string `json:",omitempty"`
+}
+
+// A VersionRange is a range of vulnerable versions. Introduced is the first
+// vulnerable version, or "" if all versions before Fixed are vulnerable.
+// Fixed is the first version that is no longer vulnerable, or "" if there is
+// no fix.
+type VersionRange struct {
+	Type       string
+	Introduced string `json:",omitempty"`
+	Fixed      string `json:",omitempty"`
+}
+
+func (r *VersionRange) String() string {
+	intro, fixed := r.Introduced, r.Fixed
+	if intro == "" {
+		intro = "0"
+	}
+	if fixed == "" {
+		return ">= " + intro
+	}
+	return ">= " + intro + ", < " + fixed
+}
+
+// SetReport attaches the issue's vulndb report, or removes it if e is nil,
+// and sets the fields derived from it.
+func (i *Issue) SetReport(e *osv.Entry) {
+	i.OSV = e
+	i.HasReport = e != nil
+	i.Affected = nil
+	if e == nil {
+		return
+	}
+	for _, a := range e.Affected {
+		af := &Affected{
+			Module:    affectedModule(a.Package.Name, i.ModulePath),
+			Package:   a.Package.Name,
+			Ecosystem: string(a.Package.Ecosystem),
+			Symbols:   a.EcosystemSpecific.Symbols,
+			GOOS:      a.EcosystemSpecific.GOOS,
+			GOARCH:    a.EcosystemSpecific.GOARCH,
+			URL:       a.DatabaseSpecific.URL,
+		}
+		for _, r := range a.Ranges {
+			af.Ranges = append(af.Ranges, pairEvents(r)...)
+		}
+		i.Affected = append(i.Affected, af)
+	}
+}
+
+// pairEvents turns the events of an OSV range into version ranges. Each
+// introduced event starts a range, which the next fixed event ends.
+func pairEvents(r osv.AffectsRange) []*VersionRange {
+	var (
+		out []*VersionRange
+		cur *VersionRange
+	)
+	for _, ev := range r.Events {
+		switch {
+		case ev.Introduced != "":
+			if cur != nil {
+				out = append(out, cur)
+			}
+			cur = &VersionRange{Type: string(r.Type), Introduced: normalizeIntroduced(ev.Introduced)}
+		case ev.Fixed != "":
+			if cur == nil {
+				cur = &VersionRange{Type: string(r.Type)}
+			}
+			cur.Fixed = ev.Fixed
+			out = append(out, cur)
+			cur = nil
+		}
+	}
+	if cur != nil {
+		out = append(out, cur)
+	}
+	return out
+}
+
+// normalizeIntroduced returns "" for the OSV version "0", which means all
+// versions.
+func normalizeIntroduced(v string) string {
+	if v == "0" {
+		return ""
+	}
+	return v
+}
+
+// affectedModule returns the module containing pkg, if it can be told from
+// the path alone or from the module path of the issue.
+func affectedModule(pkg, issueModule string) string {
+	first, _, _ := strings.Cut(pkg, "/")
+	if !strings.Contains(first, ".") {
+		return "std"
+	}
+	if issueModule != "" && (pkg == issueModule || strings.HasPrefix(pkg, issueModule+"/")) {
+		return issueModule
+	}
+	return ""
+}
+
+// Packages returns the affected packages of the issue's report, without
+// duplicates, in report order.
+func (i *Issue) Packages() []string {
+	var pkgs []string
+	seen := map[string]bool{}
+	for _, a := range i.Affected {
+		if !seen[a.Package] {
+			seen[a.Package] = true
+			pkgs = append(pkgs, a.Package)
+		}
+	}
+	return pkgs
+}
+
+// FixedVersions returns the versions that fix any of the affected packages
+// of the issue's report, without duplicates, in report order.
+func (i *Issue) FixedVersions() []string {
+	var vs []string
+	seen := map[string]bool{}
+	for _, a := range i.Affected {
+		for _, r := range a.Ranges {
+			if r.Fixed != "" && !seen[r.Fixed] {
+				seen[r.Fixed] = true
+				vs = append(vs, r.Fixed)
+			}
+		}
+	}
+	return vs
+}
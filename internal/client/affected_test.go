@@ -0,0 +1,147 @@
+// Copyright 2022 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package client
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"golang.org/x/vuln/osv"
+)
+
+// semverRange returns an OSV SEMVER range made of the events, given as
+// "i1.0.0" for introduced and "f1.2.0" for fixed.
+func semverRange(events ...string) osv.AffectsRange {
+	r := osv.AffectsRange{Type: osv.TypeSemver}
+	for _, e := range events {
+		switch e[0] {
+		case 'i':
+			r.Events = append(r.Events, osv.RangeEvent{Introduced: e[1:]})
+		case 'f':
+			r.Events = append(r.Events, osv.RangeEvent{Fixed: e[1:]})
+		}
+	}
+	return r
+}
+
+func rangesString(rs []*VersionRange) string {
+	var ss []string
+	for _, r := range rs {
+		ss = append(ss, r.String())
+	}
+	return strings.Join(ss, "; ")
+}
+
+func TestPairEvents(t *testing.T) {
+	for _, test := range []struct {
+		events []string
+		want   string
+	}{
+		{[]string{"i0", "f1.2.0"}, ">= 0, < 1.2.0"},
+		{[]string{"i1.0.0", "f1.2.0"}, ">= 1.0.0, < 1.2.0"},
+		{[]string{"i0", "f1.2.0", "i2.0.0", "f2.1.0"}, ">= 0, < 1.2.0; >= 2.0.0, < 2.1.0"},
+		{[]string{"i1.0.0"}, ">= 1.0.0"},
+		{[]string{"i0", "f1.2.0", "i2.0.0"}, ">= 0, < 1.2.0; >= 2.0.0"},
+		{[]string{"i1.0.0", "i2.0.0", "f2.1.0"}, ">= 1.0.0; >= 2.0.0, < 2.1.0"},
+		{[]string{"f1.2.0"}, ">= 0, < 1.2.0"},
+		{nil, ""},
+	} {
+		got := pairEvents(semverRange(test.events...))
+		if g := rangesString(got); g != test.want {
+			t.Errorf("%v: got %q, want %q", test.events, g, test.want)
+		}
+		for _, r := range got {
+			if r.Type != string(osv.TypeSemver) {
+				t.Errorf("%v: range %s has type %q", test.events, r, r.Type)
+			}
+			if r.Introduced == "0" {
+				t.Errorf("%v: range %s was introduced at %q, want \"\"", test.events, r, r.Introduced)
+			}
+		}
+	}
+}
+
+func TestSetReport(t *testing.T) {
+	affected := func(pkg string, r osv.AffectsRange) osv.Affected {
+		return osv.Affected{
+			Package: osv.Package{Name: pkg, Ecosystem: osv.GoEcosystem},
+			Ranges:  osv.Affects{r},
+		}
+	}
+	e := &osv.Entry{
+		ID: "GO-2022-0140",
+		Affected: []osv.Affected{
+			affected("net/http", semverRange("i0", "f1.17.12", "i1.18.0", "f1.18.4")),
+			affected("github.com/a/b/c", semverRange("i1.0.0", "f1.2.0")),
+			affected("github.com/a/b", semverRange("i1.0.0", "f1.2.0")),
+			affected("github.com/a/bc", semverRange("i0")),
+			affected("github.com/a/b/c", semverRange("i2.0.0", "f2.0.1")),
+		},
+	}
+	e.Affected[0].EcosystemSpecific = osv.EcosystemSpecific{
+		Symbols: []string{"Server.Serve"},
+		GOOS:    []string{"linux"},
+		GOARCH:  []string{"amd64"},
+	}
+	e.Affected[0].DatabaseSpecific.URL = "https://pkg.go.dev/vuln/GO-2022-0140"
+
+	i := &Issue{Number: 140, ModulePath: "github.com/a/b"}
+	i.SetReport(e)
+	if !i.HasReport || i.OSV != e {
+		t.Fatalf("HasReport = %t, OSV = %v, want the report", i.HasReport, i.OSV)
+	}
+	var got []string
+	for _, a := range i.Affected {
+		got = append(got, fmt.Sprintf("%s %s %s: %s", a.Module, a.Package, a.Ecosystem, rangesString(a.Ranges)))
+	}
+	want := []string{
+		"std net/http Go: >= 0, < 1.17.12; >= 1.18.0, < 1.18.4",
+		"github.com/a/b github.com/a/b/c Go: >= 1.0.0, < 1.2.0",
+		"github.com/a/b github.com/a/b Go: >= 1.0.0, < 1.2.0",
+		" github.com/a/bc Go: >= 0",
+		"github.com/a/b github.com/a/b/c Go: >= 2.0.0, < 2.0.1",
+	}
+	if g, w := strings.Join(got, "\n"), strings.Join(want, "\n"); g != w {
+		t.Errorf("got affected\n%s\nwant\n%s", g, w)
+	}
+	a := i.Affected[0]
+	if strings.Join(a.Symbols, ",") != "Server.Serve" || strings.Join(a.GOOS, ",") != "linux" ||
+		strings.Join(a.GOARCH, ",") != "amd64" || a.URL != e.Affected[0].DatabaseSpecific.URL {
+		t.Errorf("got %+v, want the symbols, GOOS, GOARCH and URL of the report", a)
+	}
+	if a := i.Affected[1]; a.Symbols != nil || a.GOOS != nil || a.GOARCH != nil || a.URL != "" {
+		t.Errorf("got %+v, want no symbols, GOOS, GOARCH or URL", a)
+	}
+
+	if got, want := strings.Join(i.Packages(), " "), "net/http github.com/a/b/c github.com/a/b github.com/a/bc"; got != want {
+		t.Errorf("Packages() = %s, want %s", got, want)
+	}
+	if got, want := strings.Join(i.FixedVersions(), " "), "1.17.12 1.18.4 1.2.0 2.0.1"; got != want {
+		t.Errorf("FixedVersions() = %s, want %s", got, want)
+	}
+
+	// Without a module path, only the standard library is known.
+	i = &Issue{Number: 141}
+	i.SetReport(e)
+	for _, a := range i.Affected {
+		want := ""
+		if a.Package == "net/http" {
+			want = "std"
+		}
+		if a.Module != want {
+			t.Errorf("%s: module %q, want %q", a.Package, a.Module, want)
+		}
+	}
+
+	// A nil report removes the report and what was derived from it.
+	i.SetReport(nil)
+	if i.HasReport || i.OSV != nil || i.Affected != nil {
+		t.Errorf("after SetReport(nil): HasReport = %t, OSV = %v, Affected = %v", i.HasReport, i.OSV, i.Affected)
+	}
+	if len(i.Packages()) != 0 || len(i.FixedVersions()) != 0 {
+		t.Errorf("after SetReport(nil): Packages() = %v, FixedVersions() = %v", i.Packages(), i.FixedVersions())
+	}
+}
@@ -0,0 +1,140 @@
+// Copyright 2022 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package client
+
+import (
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// A Reference is a link found in an issue body.
+type Reference struct {
+	// Type is the kind of reference, such as "ADVISORY", "FIX" or "WEB", if
+	// the body says. It is empty otherwise.
+	Type string
+	URL  string
+}
+
+// A Body holds the structured content of an issue body.
+type Body struct {
+	// Description is the description of the vulnerability: the
+	// "Description:" section if there is one, or else the text before the
+	// first section.
+	Description string
+	// References are the distinct links in the body, in order of appearance.
+	References []Reference
+	// Versions are the distinct versions mentioned outside of links, in order
+	// of appearance.
+	Versions []string
+	// LinkedIssues are the numbers of other vulndb issues mentioned, sorted.
+	LinkedIssues []int
+}
+
+var (
+	// sectionRegexp matches a line that starts a section, such as
+	// "References:" or "## Cross references".
+	sectionRegexp = regexp.MustCompile(`^(?:#+\s*)?([A-Z][A-Za-z ]*?):?\s*$`)
+	// typedRefRegexp matches a list item like "- FIX: https://...".
+	typedRefRegexp = regexp.MustCompile(`^[-*]\s*([A-Za-z_]+):\s*(https?://\S+)`)
+	urlRegexp      = regexp.MustCompile(`https?://[^\s<>()\[\]"'` + "`" + `]+`)
+	versionRegexp  = regexp.MustCompile(`\bv?\d+\.\d+(?:\.\d+)?(?:-[0-9A-Za-z.-]+)?\b`)
+	// issueRefRegexp matches "#123", "golang/vulndb#123" and links to vulndb
+	// issues.
+	issueRefRegexp = regexp.MustCompile(`(?:^|[\s(])(?:golang/vulndb)?#(\d+)\b|github\.com/golang/vulndb/issues/(\d+)`)
+)
+
+// knownSections are the section headings used by the issues that the
+// vulndb worker files. Other headings are treated as ordinary text.
+var knownSections = map[string]bool{
+	"description":      true,
+	"references":       true,
+	"cross references": true,
+	"modules":          true,
+}
+
+// ParseBody extracts the structured content from an issue body.
+func ParseBody(body string) *Body {
+	b := &Body{}
+	var (
+		section     string
+		lead, descr []string
+		inCode      bool
+	)
+	for _, line := range strings.Split(body, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if strings.HasPrefix(trimmed, "```") {
+			inCode = !inCode
+			continue
+		}
+		if !inCode {
+			if m := sectionRegexp.FindStringSubmatch(trimmed); m != nil && knownSections[strings.ToLower(m[1])] {
+				section = strings.ToLower(m[1])
+				continue
+			}
+		}
+		switch section {
+		case "":
+			if !inCode {
+				lead = append(lead, line)
+			}
+		case "description":
+			descr = append(descr, line)
+		case "references":
+			if m := typedRefRegexp.FindStringSubmatch(trimmed); m != nil {
+				b.addReference(strings.ToUpper(m[1]), trimURL(m[2]))
+			}
+		}
+	}
+	if len(descr) > 0 {
+		b.Description = strings.TrimSpace(strings.Join(descr, "\n"))
+	} else {
+		b.Description = strings.TrimSpace(strings.Join(lead, "\n"))
+	}
+
+	for _, u := range urlRegexp.FindAllString(body, -1) {
+		b.addReference("", trimURL(u))
+	}
+
+	seen := map[string]bool{}
+	for _, v := range versionRegexp.FindAllString(urlRegexp.ReplaceAllString(body, " "), -1) {
+		if !seen[v] {
+			seen[v] = true
+			b.Versions = append(b.Versions, v)
+		}
+	}
+
+	linked := map[int]bool{}
+	for _, m := range issueRefRegexp.FindAllStringSubmatch(body, -1) {
+		s := m[1]
+		if s == "" {
+			s = m[2]
+		}
+		if n, err := strconv.Atoi(s); err == nil {
+			linked[n] = true
+		}
+	}
+	for n := range linked {
+		b.LinkedIssues = append(b.LinkedIssues, n)
+	}
+	sort.Ints(b.LinkedIssues)
+	return b
+}
+
+// addReference adds a reference unless one with the same URL is present.
+func (b *Body) addReference(typ, url string) {
+	for _, r := range b.References {
+		if r.URL == url {
+			return
+		}
+	}
+	b.References = append(b.References, Reference{Type: typ, URL: url})
+}
+
+// trimURL removes trailing punctuation that is unlikely to be part of a URL.
+func trimURL(u string) string {
+	return strings.TrimRight(u, ".,;:!?")
+}
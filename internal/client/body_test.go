@@ -0,0 +1,91 @@
+// Copyright 2022 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package client
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestParseBody(t *testing.T) {
+	for _, test := range []struct {
+		name string
+		body string
+		// want is the parsed body, as by bodyString.
+		want string
+	}{
+		{
+			name: "worker format",
+			body: `In GitHub Security Advisory [GHSA-8r3f-844c-mc37](https://github.com/advisories/GHSA-8r3f-844c-mc37), there is a vulnerability in the following Go packages or modules:
+
+| Unit | Fixed | Vulnerable Ranges |
+| - | - | - |
+| [github.com/a/b](https://pkg.go.dev/github.com/a/b) | 1.2.0 | >= 1.0.3, < 1.2.0 |
+
+Description:
+A crafted request
+crashes the server.
+
+References:
+- ADVISORY: https://github.com/advisories/GHSA-8r3f-844c-mc37
+- fix: https://github.com/a/b/commit/abc123.
+- WEB: https://nvd.nist.gov/vuln/detail/CVE-2022-0001
+
+Cross references:
+- Module github.com/a/b appears in issue #141.
+`,
+			want: `description: "A crafted request\ncrashes the server."
+references: [ADVISORY https://github.com/advisories/GHSA-8r3f-844c-mc37] [FIX https://github.com/a/b/commit/abc123] [WEB https://nvd.nist.gov/vuln/detail/CVE-2022-0001] [ https://pkg.go.dev/github.com/a/b]
+versions: 1.2.0 1.0.3
+linked: [141]`,
+		},
+		{
+			name: "no sections",
+			body: "The server panics in v1.4.0-rc.1 and v1.4.0.\nSee https://example.com/report, and golang/vulndb#12 (also https://github.com/golang/vulndb/issues/7).\n",
+			want: `description: "The server panics in v1.4.0-rc.1 and v1.4.0.\nSee https://example.com/report, and golang/vulndb#12 (also https://github.com/golang/vulndb/issues/7)."
+references: [ https://example.com/report] [ https://github.com/golang/vulndb/issues/7]
+versions: v1.4.0-rc.1 v1.4.0
+linked: [7 12]`,
+		},
+		{
+			name: "markdown headings and code",
+			body: "Real text.\n```\nReferences:\n- FIX: https://example.com/in-code\n```\n## Notes\nMore text.\n## References\n- fix: https://example.com/fix\n",
+			want: `description: "Real text.\n## Notes\nMore text."
+references: [FIX https://example.com/fix] [ https://example.com/in-code]
+versions:
+linked: []`,
+		},
+		{
+			name: "empty",
+			body: "",
+			want: `description: ""
+references:
+versions:
+linked: []`,
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			if got := bodyString(ParseBody(test.body)); got != test.want {
+				t.Errorf("got\n%s\nwant\n%s", got, test.want)
+			}
+		})
+	}
+}
+
+// bodyString formats b on one line per field.
+func bodyString(b *Body) string {
+	var refs []string
+	for _, r := range b.References {
+		refs = append(refs, fmt.Sprintf("[%s %s]", r.Type, r.URL))
+	}
+	lines := []string{
+		fmt.Sprintf("description: %q", b.Description),
+		strings.TrimSpace("references: " + strings.Join(refs, " ")),
+		strings.TrimSpace("versions: " + strings.Join(b.Versions, " ")),
+		fmt.Sprintf("linked: %v", b.LinkedIssues),
+	}
+	return strings.Join(lines, "\n")
+}
@@ -8,12 +8,16 @@ package client
 import (
 	"context"
 	"fmt"
-	"regexp"
+	"net/http"
+	"sort"
+	"strconv"
 	"strings"
 	"time"
 
 	"github.com/google/go-github/v41/github"
+	"github.com/google/go-querystring/query"
 	"github.com/julieqiu/derrors"
+	log "github.com/julieqiu/dlog"
 	"github.com/shurcooL/githubv4"
 	"golang.org/x/oauth2"
 	"golang.org/x/vuln/osv"
@@ -21,130 +25,370 @@ import (
 
 // An Issue represents a GitHub issue or similar.
 type Issue struct {
-	Number      int
-	Title       string
-	Body        string
-	Labels      map[string]bool
-	CreatedAt   time.Time
-	ModulePath  string
-	PackagePath string
-	Introduced  []string
-	Fixed       []string
-	CVE         string
-	GHSA        string
-	IsStdLib    bool
-	Open        bool
-	HasReport   bool
-	OSV         *osv.Entry
+	Number    int
+	Title     string
+	Body      string
+	Labels    map[string]bool
+	CreatedAt time.Time
+	UpdatedAt time.Time
+	// ClosedAt is zero if the issue has never been closed.
+	ClosedAt time.Time
+	// StateReason says why the issue is in its current state:
+	// "completed", "not_planned" or "reopened". It may be empty.
+	StateReason string
+	// Author is the login of the user who created the issue.
+	Author string
+	// Assignees are the logins of the users assigned to the issue.
+	Assignees []string
+	// Milestone is the title of the issue's milestone, if any.
+	Milestone string
+	// Comments is the number of comments on the issue.
+	Comments   int
+	ModulePath string
+	// ModuleVersion is the version given with the module path in the
+	// title, if any.
+	ModuleVersion string
+	// Aliases are all the CVE, GHSA and GO IDs for the issue, in order of
+	// appearance.
+	Aliases []string
+	// CVE and GHSA are the first CVE and GHSA in Aliases.
+	CVE  string
+	GHSA string
+	// Description, References, Versions and LinkedIssues are parsed from
+	// Body; see ParseBody.
+	Description  string
+	References   []Reference
+	Versions     []string
+	LinkedIssues []int
+	// PullRequests are the numbers of the open pull requests in the
+	// repository whose body mentions the issue, such as with
+	// "Fixes golang/vulndb#123", sorted. ListByRepoSince only sees the pull
+	// requests updated since its argument; the store keeps track of the
+	// others.
+	PullRequests []int
+	IsStdLib     bool
+	Open         bool
+	HasReport    bool
+	OSV          *osv.Entry
+	// Affected are the packages affected according to OSV; see SetReport.
+	Affected []*Affected
+	// Events are the issue's timeline, oldest first. ListByRepo does not
+	// fetch them; see ListIssueEvents.
+	Events []*IssueEvent
 }
 
-func (i *Issue) LabeledNotGoVuln() bool {
-	return i.Labels["NotGoVuln"]
-}
-
-func (i *Issue) LabeledNeedsReport() bool {
-	return i.Labels["NeedsReport"]
-}
-
-func (i *Issue) LabeledDuplicate() bool {
-	return i.Labels["duplicate"]
+// ReportIssueNumber returns the number of the issue that the vulndb report
+// with the given ID, such as "GO-2022-0123", was created for.
+func ReportIssueNumber(id string) (int, error) {
+	parts := strings.Split(id, "-")
+	if len(parts) != 3 || parts[0] != "GO" {
+		return 0, fmt.Errorf("unexpected vulndb ID %q", id)
+	}
+	n, err := strconv.Atoi(parts[2])
+	if err != nil {
+		return 0, fmt.Errorf("unexpected vulndb ID %q: %v", id, err)
+	}
+	return n, nil
 }
 
-func (i *Issue) LabeledStdLib() bool {
-	return i.Labels["stdlib"]
+// LabelNames are the names of the labels that mean something to the triage
+// process. Forks of the vulndb repository may name them differently.
+type LabelNames struct {
+	NotGoVuln      string
+	NeedsReport    string
+	Duplicate      string
+	StdLib         string
+	NeedsCVEID     string
+	NeedsCVERecord string
 }
 
-func (i *Issue) LabeledNeedsCVEID() bool {
-	return i.Labels["NeedsCVEID"]
+// DefaultLabelNames are the label names used by golang/vulndb.
+var DefaultLabelNames = LabelNames{
+	NotGoVuln:      "NotGoVuln",
+	NeedsReport:    "NeedsReport",
+	Duplicate:      "duplicate",
+	StdLib:         "stdlib",
+	NeedsCVEID:     "NeedsCVEID",
+	NeedsCVERecord: "NeedsCVERecord",
 }
 
-func (i *Issue) LabeledNeedsCVERecord() bool {
-	return i.Labels["NeedsCVERecord"]
+// WithDefaults returns n with its empty fields set from DefaultLabelNames.
+func (n LabelNames) WithDefaults() LabelNames {
+	d := DefaultLabelNames
+	for _, f := range []struct{ name, def *string }{
+		{&n.NotGoVuln, &d.NotGoVuln},
+		{&n.NeedsReport, &d.NeedsReport},
+		{&n.Duplicate, &d.Duplicate},
+		{&n.StdLib, &d.StdLib},
+		{&n.NeedsCVEID, &d.NeedsCVEID},
+		{&n.NeedsCVERecord, &d.NeedsCVERecord},
+	} {
+		if *f.name == "" {
+			*f.name = *f.def
+		}
+	}
+	return n
 }
 
 type Client struct {
-	client *github.Client
-	ghsa   *githubv4.Client
-	owner  string
-	repo   string
+	client    *github.Client
+	ghsa      *githubv4.Client
+	transport *transport
+	parser    *IssueParser
+	labels    LabelNames
+	owner     string
+	repo      string
 }
 
 // New creates a Client that will create issues in
 // the a GitHub repo.
-// A GitHub access token is required to create issues.
-func New(ctx context.Context, owner, repo, accessToken string) *Client {
-	ts := oauth2.StaticTokenSource(&oauth2.Token{AccessToken: accessToken})
-	tc := oauth2.NewClient(ctx, ts)
-	return &Client{
-		client: github.NewClient(tc),
-		owner:  owner,
-		repo:   repo,
-		ghsa:   githubv4.NewClient(tc),
+// A GitHub access token is required to create issues. If accessToken is
+// empty, requests are made without authentication.
+func New(ctx context.Context, owner, repo, accessToken string, opts ...Option) (_ *Client, err error) {
+	defer derrors.Wrap(&err, "client.New(%q, %q)", owner, repo)
+
+	var o options
+	for _, opt := range opts {
+		opt(&o)
+	}
+	// Copy the client, so that its timeout, cookie jar and redirect policy
+	// are kept while its transport is wrapped.
+	tc := &http.Client{}
+	if o.httpClient != nil {
+		*tc = *o.httpClient
+	}
+	if accessToken != "" {
+		tc.Transport = &oauth2.Transport{
+			Source: oauth2.StaticTokenSource(&oauth2.Token{AccessToken: accessToken}),
+			Base:   tc.Transport,
+		}
+	}
+	t := newTransport(tc.Transport)
+	t.userAgent = o.userAgent
+	tc.Transport = t
+
+	gh := github.NewClient(tc)
+	if o.restURL != "" {
+		u, err := parseBaseURL(o.restURL)
+		if err != nil {
+			return nil, err
+		}
+		gh.BaseURL = u
+	}
+	if o.userAgent != "" {
+		gh.UserAgent = o.userAgent
+	}
+	parser := o.parser
+	if parser == nil {
+		if parser, err = NewIssueParser(); err != nil {
+			return nil, err
+		}
 	}
+	ghsa := githubv4.NewClient(tc)
+	if o.graphQLURL != "" {
+		ghsa = githubv4.NewEnterpriseClient(o.graphQLURL, tc)
+	}
+	return &Client{
+		client:    gh,
+		owner:     owner,
+		repo:      repo,
+		ghsa:      ghsa,
+		transport: t,
+		parser:    parser,
+		labels:    o.labels.WithDefaults(),
+	}, nil
+}
+
+// RateLimits returns the API budget most recently reported by GitHub for
+// each resource used by the client, and the number of retried requests.
+func (c *Client) RateLimits() []RateLimit {
+	return c.transport.rateLimits()
+}
+
+// A MalformedIssue is an issue that could not be parsed, and which needs to
+// be fixed by hand.
+type MalformedIssue struct {
+	Number int
+	Title  string
+	Open   bool
+	// Reason explains what is wrong with the issue.
+	Reason string
 }
 
-// ListByRepo lists the issues for the repository.
+// ListByRepo lists the issues for the repository. Issues that cannot be
+// parsed are returned separately rather than causing an error.
 //
 // GitHub API docs: https://docs.github.com/en/free-pro-team@latest/rest/reference/issues/#list-repository-issues
-func (c *Client) ListByRepo(ctx context.Context) (_ []*Issue, err error) {
+func (c *Client) ListByRepo(ctx context.Context) (_ []*Issue, _ []*MalformedIssue, err error) {
 	defer derrors.Wrap(&err, "ListByRepo(ctx)")
-	opts := &github.IssueListByRepoOptions{}
+	return c.ListByRepoSince(ctx, time.Time{})
+}
 
-	opts.State = "open"
-	open, err := c.listByRepo(ctx, opts)
+// ListByRepoSince lists the issues for the repository that were updated at
+// or after since. If since is zero, all issues are listed. Issues that cannot
+// be parsed are returned separately rather than causing an error.
+//
+// The PullRequests field of each issue is set from all the open pull
+// requests, whether or not they were updated since.
+//
+// GitHub API docs: https://docs.github.com/en/free-pro-team@latest/rest/reference/issues/#list-repository-issues
+func (c *Client) ListByRepoSince(ctx context.Context, since time.Time) (_ []*Issue, _ []*MalformedIssue, err error) {
+	defer derrors.Wrap(&err, "ListByRepoSince(ctx, %v)", since)
+	issues, malformed, prs, err := c.ListIssuesAndPRsSince(ctx, since)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
+	if !since.IsZero() {
+		// A pull request that was not updated since may still be open.
+		if prs, err = c.ListOpenPullRequests(ctx); err != nil {
+			return nil, nil, err
+		}
+	}
+	SetPullRequests(issues, prs)
+	return issues, malformed, nil
+}
 
-	opts.State = "closed"
-	closed, err := c.listByRepo(ctx, opts)
+// ListOpenPullRequests lists the open pull requests in the repository.
+//
+// GitHub API docs: https://docs.github.com/en/free-pro-team@latest/rest/reference/issues/#list-repository-issues
+func (c *Client) ListOpenPullRequests(ctx context.Context) (_ []*PullRequest, err error) {
+	defer derrors.Wrap(&err, "ListOpenPullRequests")
+	all, err := c.listByRepo(ctx, &github.IssueListByRepoOptions{State: "open"})
 	if err != nil {
 		return nil, err
 	}
-	fmt.Printf("%d open issues, including PRs\n", len(open))
-	fmt.Printf("%d closed issues, including PRs\n", len(closed))
+	var prs []*PullRequest
+	for _, issue := range all {
+		if issue.IsPullRequest() {
+			prs = append(prs, newPullRequest(issue))
+		}
+	}
+	return prs, nil
+}
+
+// A PullRequest is a pull request in the repository.
+type PullRequest struct {
+	Number int
+	Open   bool
+	// LinkedIssues are the issues that the body of the pull request
+	// mentions; see ParseBody.
+	LinkedIssues []int
+}
 
-	for k, v := range closed {
-		open[k] = v
+func newPullRequest(issue *ghIssue) *PullRequest {
+	return &PullRequest{
+		Number:       issue.GetNumber(),
+		Open:         issue.GetState() == "open",
+		LinkedIssues: ParseBody(issue.GetBody()).LinkedIssues,
 	}
-	fmt.Printf("%d total issues, including PRs\n", len(open))
+}
+
+// SetPullRequests sets the PullRequests field of each issue to the open pull
+// requests in prs that mention it.
+func SetPullRequests(issues []*Issue, prs []*PullRequest) {
+	prsFor := map[int][]int{}
+	for _, pr := range prs {
+		if pr.Open {
+			for _, n := range pr.LinkedIssues {
+				prsFor[n] = append(prsFor[n], pr.Number)
+			}
+		}
+	}
+	for _, i := range issues {
+		i.PullRequests = prsFor[i.Number]
+		sort.Ints(i.PullRequests)
+	}
+}
+
+// ListIssuesAndPRsSince is like ListByRepoSince, but it also returns the pull
+// requests that were updated at or after since, open or closed, and it does
+// not set the PullRequests field of the issues.
+//
+// GitHub API docs: https://docs.github.com/en/free-pro-team@latest/rest/reference/issues/#list-repository-issues
+func (c *Client) ListIssuesAndPRsSince(ctx context.Context, since time.Time) (_ []*Issue, _ []*MalformedIssue, _ []*PullRequest, err error) {
+	defer derrors.Wrap(&err, "ListIssuesAndPRsSince(ctx, %v)", since)
+	opts := &github.IssueListByRepoOptions{
+		State: "all",
+		Since: since,
+	}
+	all, err := c.listByRepo(ctx, opts)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	log.Infof(ctx, "%d issues updated since %v, including PRs", len(all), since)
 
 	var (
-		out   []*Issue
-		dummy int
-		prs   int
+		out       []*Issue
+		malformed []*MalformedIssue
+		prs       []*PullRequest
+		dummy     int
 	)
-	for _, issue := range open {
+	for _, issue := range all {
 		if issue.IsPullRequest() {
-			prs += 1
+			prs = append(prs, newPullRequest(issue))
 			continue
 		}
 		if *issue.Number <= 139 {
 			dummy += 1
 			if *issue.State == "open" {
-				fmt.Println("open: ", *issue.Number)
+				log.Infof(ctx, "dummy issue %d is open", *issue.Number)
 			}
 			continue
 		}
-		i2, err := constructIssue(issue)
+		i2, err := constructIssue(issue, c.parser, c.labels)
 		if err != nil {
-			return nil, err
+			malformed = append(malformed, &MalformedIssue{
+				Number: issue.GetNumber(),
+				Title:  issue.GetTitle(),
+				Open:   issue.GetState() == "open",
+				Reason: err.Error(),
+			})
+			continue
 		}
 		out = append(out, i2)
 	}
-	fmt.Printf("%d dummy issues (skipped)\n", dummy)
-	fmt.Printf("%d PRs (skipped) \n", prs)
-	return out, nil
+	log.Infof(ctx, "%d dummy issues (skipped)", dummy)
+	log.Infof(ctx, "%d PRs", len(prs))
+	log.Infof(ctx, "%d malformed issues", len(malformed))
+	return out, malformed, prs, nil
 }
 
-func (c *Client) listByRepo(ctx context.Context, opts *github.IssueListByRepoOptions) (_ map[int]*github.Issue, err error) {
+// A ghIssue is a github.Issue with the fields that our version of go-github
+// does not know about.
+type ghIssue struct {
+	github.Issue
+	// StateReason is "completed", "not_planned" or "reopened".
+	StateReason *string `json:"state_reason,omitempty"`
+}
+
+// GetStateReason returns the StateReason field if it's non-nil, zero value
+// otherwise.
+func (i *ghIssue) GetStateReason() string {
+	if i == nil || i.StateReason == nil {
+		return ""
+	}
+	return *i.StateReason
+}
+
+func (c *Client) listByRepo(ctx context.Context, opts *github.IssueListByRepoOptions) (_ map[int]*ghIssue, err error) {
 	opts.ListOptions = github.ListOptions{Page: 1, PerPage: 100}
-	out := map[int]*github.Issue{}
+	out := map[int]*ghIssue{}
 	for {
-		issues, _, err := c.client.Issues.ListByRepo(ctx, c.owner, c.repo, opts)
+		// Make the request ourselves rather than calling
+		// c.client.Issues.ListByRepo, so that we can decode into ghIssue.
+		v, err := query.Values(opts)
+		if err != nil {
+			return nil, err
+		}
+		u := fmt.Sprintf("repos/%s/%s/issues?%s", c.owner, c.repo, v.Encode())
+		req, err := c.client.NewRequest(http.MethodGet, u, nil)
 		if err != nil {
 			return nil, err
 		}
+		var issues []*ghIssue
+		if _, err := c.client.Do(ctx, req, &issues); err != nil {
+			return nil, err
+		}
 		if len(issues) == 0 {
 			break
 		}
@@ -156,60 +400,55 @@ func (c *Client) listByRepo(ctx context.Context, opts *github.IssueListByRepoOpt
 	return out, nil
 }
 
-func constructIssue(issue *github.Issue) (*Issue, error) {
+func constructIssue(issue *ghIssue, parser *IssueParser, names LabelNames) (*Issue, error) {
 	i2 := &Issue{
-		Number:    *issue.Number,
-		Title:     *issue.Title,
-		CreatedAt: *issue.CreatedAt,
-		Body:      *issue.Body,
-		Labels:    map[string]bool{},
+		Number:      issue.GetNumber(),
+		Title:       issue.GetTitle(),
+		CreatedAt:   issue.GetCreatedAt(),
+		UpdatedAt:   issue.GetUpdatedAt(),
+		ClosedAt:    issue.GetClosedAt(),
+		StateReason: issue.GetStateReason(),
+		Author:      issue.GetUser().GetLogin(),
+		Milestone:   issue.GetMilestone().GetTitle(),
+		Comments:    issue.GetComments(),
+		Body:        issue.GetBody(),
+		Labels:      map[string]bool{},
 	}
-	isl, err := isStdLib(issue)
-	if err != nil {
-		return nil, err
-	}
-	i2.IsStdLib = isl
 	for _, l := range issue.Labels {
-		i2.Labels[*l.Name] = true
-	}
-	if *issue.State == "open" {
-		i2.Open = true
+		i2.Labels[l.GetName()] = true
 	}
-
-	mp, cve, err := parseModulePathAndCVE(*issue.Title)
-	if err != nil {
-		return nil, err
+	for _, u := range issue.Assignees {
+		i2.Assignees = append(i2.Assignees, u.GetLogin())
 	}
-	i2.ModulePath = mp
-	if strings.Contains(cve, "CVE") {
-		i2.CVE = cve
-	} else {
-		i2.GHSA = cve
+	if issue.GetState() == "open" {
+		i2.Open = true
 	}
-	return i2, nil
-}
 
-func isStdLib(issue *github.Issue) (bool, error) {
-	for _, label := range issue.Labels {
-		if *label.Name == "stdlib" {
-			return true, nil
+	b := ParseBody(i2.Body)
+	i2.Description = b.Description
+	i2.References = b.References
+	i2.Versions = b.Versions
+	for _, n := range b.LinkedIssues {
+		if n != i2.Number {
+			i2.LinkedIssues = append(i2.LinkedIssues, n)
 		}
 	}
-	mp, _, err := parseModulePathAndCVE(*issue.Title)
+
+	pi, err := parser.Parse(i2.Title, i2.Body)
 	if err != nil {
-		return false, err
+		return nil, err
 	}
-	return !strings.Contains(mp, "."), nil
-}
-
-var titleRegexp = regexp.MustCompile(`^x\/vulndb: potential Go vuln in (.+): (.*)$`)
-
-func parseModulePathAndCVE(title string) (string, string, error) {
-	m := titleRegexp.FindStringSubmatch(title)
-	if len(m) != 3 {
-		fmt.Println(m)
-		return "", "", fmt.Errorf("%q: not a valid title", title)
+	i2.ModulePath = pi.ModulePath
+	i2.ModuleVersion = pi.ModuleVersion
+	i2.IsStdLib = i2.Labels[names.StdLib] || !strings.Contains(pi.ModulePath, ".")
+	i2.Aliases = pi.IDs
+	for _, id := range pi.IDs {
+		switch {
+		case strings.HasPrefix(id, "CVE-") && i2.CVE == "":
+			i2.CVE = id
+		case strings.HasPrefix(id, "GHSA-") && i2.GHSA == "":
+			i2.GHSA = id
+		}
 	}
-	mp := strings.TrimSuffix(strings.TrimPrefix(m[1], `"`), `"`)
-	return mp, m[2], nil
+	return i2, nil
 }
@@ -0,0 +1,326 @@
+// Copyright 2022 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-github/v41/github"
+	"github.com/julieqiu/github/internal/githubtest"
+)
+
+// newTestClient returns a Client for a fake server loaded with the fixtures
+// in ../githubtest/testdata.
+func newTestClient(t *testing.T) (*Client, *githubtest.Server) {
+	t.Helper()
+	srv := githubtest.NewServer("golang", "vulndb")
+	t.Cleanup(srv.Close)
+	if err := srv.LoadIssues("../githubtest/testdata/issues.json"); err != nil {
+		t.Fatal(err)
+	}
+	if err := srv.LoadAdvisories("../githubtest/testdata/advisories.json"); err != nil {
+		t.Fatal(err)
+	}
+	c, err := New(context.Background(), "golang", "vulndb", "tok",
+		WithHTTPClient(srv.HTTPClient()), WithRESTURL(srv.RESTURL()), WithGraphQLURL(srv.GraphQLURL()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return c, srv
+}
+
+func TestNewKeepsHTTPClient(t *testing.T) {
+	var auth string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		auth = r.Header.Get("Authorization")
+		http.Redirect(w, r, "/elsewhere", http.StatusFound)
+	}))
+	defer srv.Close()
+
+	errNoRedirects := errors.New("no redirects")
+	hc := &http.Client{
+		CheckRedirect: func(*http.Request, []*http.Request) error { return errNoRedirects },
+	}
+	ctx := context.Background()
+	c, err := New(ctx, "golang", "vulndb", "tok", WithHTTPClient(hc), WithRESTURL(srv.URL))
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = c.ListIssueEvents(ctx, 1)
+	if err == nil || !strings.Contains(err.Error(), errNoRedirects.Error()) {
+		t.Errorf("got error %v, want one from the client's CheckRedirect", err)
+	}
+	if want := "Bearer tok"; auth != want {
+		t.Errorf("Authorization = %q, want %q", auth, want)
+	}
+}
+
+func TestListByRepo(t *testing.T) {
+	c, srv := newTestClient(t)
+	// Fill three pages of 100, with issues in both states.
+	created := time.Date(2022, 4, 1, 0, 0, 0, 0, time.UTC)
+	for n := 150; n < 350; n++ {
+		state := "open"
+		if n%2 == 0 {
+			state = "closed"
+		}
+		srv.AddIssues(&github.Issue{
+			Number:    github.Int(n),
+			Title:     github.String(fmt.Sprintf("x/vulndb: potential Go vuln in github.com/example/m%d: CVE-2022-%04d", n, n)),
+			State:     github.String(state),
+			CreatedAt: &created,
+		})
+	}
+	before := srv.Requests()
+	issues, malformed, err := c.ListByRepo(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Three pages, and the empty page that ends the listing.
+	if got, want := srv.Requests()-before, 4; got != want {
+		t.Errorf("made %d requests, want %d", got, want)
+	}
+	if len(malformed) != 0 {
+		t.Errorf("malformed = %v, want none", malformed)
+	}
+	// 140-143 from the fixture, and the 200 added; 144 is a PR.
+	if got, want := len(issues), 204; got != want {
+		t.Fatalf("got %d issues, want %d", got, want)
+	}
+	byNumber := map[int]*Issue{}
+	for _, i := range issues {
+		byNumber[i.Number] = i
+	}
+	for _, test := range []struct {
+		number int
+		open   bool
+		module string
+		ghsa   string
+	}{
+		{140, false, "github.com/example/one", ""},
+		{141, true, "github.com/example/two", "GHSA-8r3f-844c-mc37"},
+		{150, false, "github.com/example/m150", ""},
+		{151, true, "github.com/example/m151", ""},
+		{349, true, "github.com/example/m349", ""},
+	} {
+		i := byNumber[test.number]
+		if i == nil {
+			t.Errorf("#%d missing", test.number)
+			continue
+		}
+		if i.Open != test.open || i.ModulePath != test.module || i.GHSA != test.ghsa {
+			t.Errorf("#%d: got open=%t module=%q GHSA=%q, want %t, %q, %q",
+				test.number, i.Open, i.ModulePath, i.GHSA, test.open, test.module, test.ghsa)
+		}
+	}
+}
+
+func TestListGHSAs(t *testing.T) {
+	c, srv := newTestClient(t)
+	// One advisory per page, so that the cursor is followed.
+	srv.SetPageSize(1)
+	ctx := context.Background()
+	for _, test := range []struct {
+		since time.Time
+		want  []string
+	}{
+		{time.Time{}, []string{"GHSA-8r3f-844c-mc37", "GHSA-vp56-6g26-6827"}},
+		{time.Date(2022, 1, 10, 0, 0, 0, 0, time.UTC), []string{"GHSA-8r3f-844c-mc37"}},
+		{time.Date(2022, 2, 1, 0, 0, 0, 0, time.UTC), nil},
+	} {
+		sas, err := c.ListGHSAs(ctx, test.since)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var got []string
+		for _, sa := range sas {
+			got = append(got, sa.PrettyID())
+		}
+		if strings.Join(got, " ") != strings.Join(test.want, " ") {
+			t.Errorf("since %v: got %v, want %v", test.since, got, test.want)
+		}
+	}
+}
+
+func TestListGHSAForCVE(t *testing.T) {
+	c, _ := newTestClient(t)
+	sas, err := c.ListGHSAForCVE(context.Background(), "CVE-2022-0001")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(sas) != 1 || sas[0].PrettyID() != "GHSA-vp56-6g26-6827" {
+		t.Fatalf("got %v, want GHSA-vp56-6g26-6827", sas)
+	}
+	// Only the Go package of the advisory is included.
+	if vs := sas[0].Vulns; len(vs) != 1 || vs[0].Package != "github.com/example/one" {
+		t.Errorf("vulns = %+v, want only github.com/example/one", vs)
+	}
+}
+
+func TestFetchGHSA(t *testing.T) {
+	c, _ := newTestClient(t)
+	ctx := context.Background()
+	sa, err := c.FetchGHSA(ctx, "GHSA-8r3f-844c-mc37")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sa.Summary != "Path traversal in github.com/example/two" || len(sa.Vulns) != 1 ||
+		sa.Vulns[0].VulnerableVersionRange != ">= 1.0.3, < 1.2.0" || sa.Vulns[0].EarliestFixedVersion != "1.2.0" {
+		t.Errorf("got %+v", sa)
+	}
+	if _, err := c.FetchGHSA(ctx, "GHSA-2222-3333-4444"); err == nil || !strings.Contains(err.Error(), "Could not resolve") {
+		t.Errorf("fetching a missing GHSA: got error %v, want not found", err)
+	}
+}
+
+func TestReplaceLabels(t *testing.T) {
+	c, _ := newTestClient(t)
+	ctx := context.Background()
+	labelsOf := func(number int) string {
+		t.Helper()
+		issues, _, err := c.ListByRepo(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, i := range issues {
+			if i.Number == number {
+				var ls []string
+				for l := range i.Labels {
+					ls = append(ls, l)
+				}
+				sort.Strings(ls)
+				return strings.Join(ls, ",")
+			}
+		}
+		t.Fatalf("#%d missing", number)
+		return ""
+	}
+	if err := c.ReplaceLabels(ctx, 142, []string{"NotGoVuln", "stdlib"}); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := labelsOf(142), "NotGoVuln,stdlib"; got != want {
+		t.Errorf("labels = %q, want %q", got, want)
+	}
+	if err := c.ReplaceLabels(ctx, 142, nil); err != nil {
+		t.Fatal(err)
+	}
+	if got := labelsOf(142); got != "" {
+		t.Errorf("labels = %q, want none", got)
+	}
+}
+
+func TestListIssuesAndPRsSince(t *testing.T) {
+	c, srv := newTestClient(t)
+	created := time.Date(2022, 4, 1, 0, 0, 0, 0, time.UTC)
+	srv.AddIssues(&github.Issue{
+		Number:           github.Int(146),
+		Title:            github.String("data/reports: add GO-2022-0141"),
+		Body:             github.String("Fixes golang/vulndb#141"),
+		State:            github.String("open"),
+		PullRequestLinks: &github.PullRequestLinks{URL: github.String("https://api.github.com/repos/golang/vulndb/pulls/146")},
+		CreatedAt:        &created,
+	})
+	ctx := context.Background()
+	issues, _, prs, err := c.ListIssuesAndPRsSince(ctx, time.Time{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, pr := range prs {
+		got = append(got, fmt.Sprintf("#%d open=%t links=%v", pr.Number, pr.Open, pr.LinkedIssues))
+	}
+	sort.Strings(got)
+	if want := "#144 open=false links=[]; #146 open=true links=[141]"; strings.Join(got, "; ") != want {
+		t.Errorf("got pull requests %q, want %q", strings.Join(got, "; "), want)
+	}
+	for _, i := range issues {
+		if len(i.PullRequests) != 0 {
+			t.Errorf("#%d: PullRequests = %v, want unset", i.Number, i.PullRequests)
+		}
+	}
+
+	// ListByRepoSince links the open pull request to its issue.
+	issues, _, err = c.ListByRepoSince(ctx, time.Time{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, i := range issues {
+		want := 0
+		if i.Number == 141 {
+			want = 1
+		}
+		if len(i.PullRequests) != want {
+			t.Errorf("#%d: PullRequests = %v", i.Number, i.PullRequests)
+		}
+	}
+}
+
+func TestWithLabelNames(t *testing.T) {
+	srv := githubtest.NewServer("golang", "vulndb")
+	defer srv.Close()
+	created := time.Date(2022, 4, 1, 0, 0, 0, 0, time.UTC)
+	for n, label := range map[int]string{150: "std", 151: "stdlib"} {
+		srv.AddIssues(&github.Issue{
+			Number:    github.Int(n),
+			Title:     github.String("x/vulndb: potential Go vuln in github.com/example/m: CVE-2022-0150"),
+			State:     github.String("open"),
+			Labels:    []*github.Label{{Name: github.String(label)}},
+			CreatedAt: &created,
+		})
+	}
+	c, err := New(context.Background(), "golang", "vulndb", "tok",
+		WithHTTPClient(srv.HTTPClient()), WithRESTURL(srv.RESTURL()), WithLabelNames(LabelNames{StdLib: "std"}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	issues, _, err := c.ListByRepo(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, i := range issues {
+		if want := i.Number == 150; i.IsStdLib != want {
+			t.Errorf("#%d labeled %v: IsStdLib = %t, want %t", i.Number, i.Labels, i.IsStdLib, want)
+		}
+	}
+}
+
+func TestListByRepoSinceLinksOlderPRs(t *testing.T) {
+	c, srv := newTestClient(t)
+	created := time.Date(2022, 1, 15, 0, 0, 0, 0, time.UTC)
+	srv.AddIssues(&github.Issue{
+		Number:           github.Int(146),
+		Title:            github.String("data/reports: add GO-2022-0141"),
+		Body:             github.String("Fixes golang/vulndb#141"),
+		State:            github.String("open"),
+		PullRequestLinks: &github.PullRequestLinks{URL: github.String("https://api.github.com/repos/golang/vulndb/pulls/146")},
+		CreatedAt:        &created,
+	})
+	// The pull request was last updated before since, but still links #141.
+	since := time.Date(2022, 2, 1, 0, 0, 0, 0, time.UTC)
+	issues, _, err := c.ListByRepoSince(context.Background(), since)
+	if err != nil {
+		t.Fatal(err)
+	}
+	found := false
+	for _, i := range issues {
+		if i.Number == 141 {
+			found = true
+			if len(i.PullRequests) != 1 || i.PullRequests[0] != 146 {
+				t.Errorf("#141: PullRequests = %v, want [146]", i.PullRequests)
+			}
+		}
+	}
+	if !found {
+		t.Error("#141 not listed")
+	}
+}
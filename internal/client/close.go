@@ -0,0 +1,32 @@
+// Copyright 2022 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package client
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"github.com/julieqiu/derrors"
+)
+
+// CloseIssue closes an issue with the given state reason, "completed" or
+// "not_planned". If reason is empty, GitHub uses "completed".
+//
+// GitHub API docs: https://docs.github.com/en/free-pro-team@latest/rest/reference/issues/#update-an-issue
+func (c *Client) CloseIssue(ctx context.Context, number int, reason string) (err error) {
+	defer derrors.Wrap(&err, "CloseIssue(ctx, %d, %q)", number, reason)
+	// github.IssueRequest has no state_reason field.
+	body := struct {
+		State       string `json:"state"`
+		StateReason string `json:"state_reason,omitempty"`
+	}{State: "closed", StateReason: reason}
+	req, err := c.client.NewRequest(http.MethodPatch, fmt.Sprintf("repos/%s/%s/issues/%d", c.owner, c.repo, number), body)
+	if err != nil {
+		return err
+	}
+	_, err = c.client.Do(ctx, req, nil)
+	return err
+}
@@ -0,0 +1,21 @@
+// Copyright 2022 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package client
+
+import (
+	"context"
+
+	"github.com/google/go-github/v41/github"
+	"github.com/julieqiu/derrors"
+)
+
+// AddComment adds a comment with the given Markdown body to an issue.
+//
+// GitHub API docs: https://docs.github.com/en/free-pro-team@latest/rest/reference/issues/#create-an-issue-comment
+func (c *Client) AddComment(ctx context.Context, number int, body string) (err error) {
+	defer derrors.Wrap(&err, "AddComment(ctx, %d)", number)
+	_, _, err = c.client.Issues.CreateComment(ctx, c.owner, c.repo, number, &github.IssueComment{Body: github.String(body)})
+	return err
+}
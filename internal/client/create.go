@@ -0,0 +1,33 @@
+// Copyright 2022 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package client
+
+import (
+	"context"
+
+	"github.com/google/go-github/v41/github"
+	"github.com/julieqiu/derrors"
+)
+
+// CreateIssue creates an issue with the given title, body and labels, and
+// returns its number. The request is not retried, so that a failure after
+// GitHub has created the issue cannot create it twice.
+//
+// GitHub API docs: https://docs.github.com/en/free-pro-team@latest/rest/reference/issues/#create-an-issue
+func (c *Client) CreateIssue(ctx context.Context, title, body string, labels []string) (_ int, err error) {
+	defer derrors.Wrap(&err, "CreateIssue(ctx, %q)", title)
+	req := &github.IssueRequest{
+		Title: github.String(title),
+		Body:  github.String(body),
+	}
+	if len(labels) > 0 {
+		req.Labels = &labels
+	}
+	iss, _, err := c.client.Issues.Create(ctx, c.owner, c.repo, req)
+	if err != nil {
+		return 0, err
+	}
+	return iss.GetNumber(), nil
+}
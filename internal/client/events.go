@@ -0,0 +1,78 @@
+// Copyright 2022 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package client
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/google/go-github/v41/github"
+	"github.com/julieqiu/derrors"
+)
+
+// An IssueEvent is something that happened to an issue after it was
+// created.
+type IssueEvent struct {
+	// Type is one of the event types in TimelineEventTypes.
+	Type string
+	// Actor is the login of the user who caused the event.
+	Actor string
+	// Label is the label added or removed, for "labeled" and "unlabeled"
+	// events.
+	Label string
+	// Assignee is the login of the user assigned or unassigned, for
+	// "assigned" and "unassigned" events.
+	Assignee  string
+	CreatedAt time.Time
+}
+
+// TimelineEventTypes are the types of event kept by ListIssueEvents. Other
+// events, such as mentions and renames, are dropped.
+var TimelineEventTypes = map[string]bool{
+	"labeled":    true,
+	"unlabeled":  true,
+	"closed":     true,
+	"reopened":   true,
+	"assigned":   true,
+	"unassigned": true,
+}
+
+// ListIssueEvents lists the events of an issue whose type is in
+// TimelineEventTypes, oldest first.
+//
+// GitHub API docs: https://docs.github.com/en/free-pro-team@latest/rest/reference/issues/#list-issue-events
+func (c *Client) ListIssueEvents(ctx context.Context, number int) (_ []*IssueEvent, err error) {
+	defer derrors.Wrap(&err, "ListIssueEvents(ctx, %d)", number)
+
+	opts := &github.ListOptions{Page: 1, PerPage: 100}
+	var out []*IssueEvent
+	for {
+		events, resp, err := c.client.Issues.ListIssueEvents(ctx, c.owner, c.repo, number, opts)
+		if err != nil {
+			return nil, err
+		}
+		for _, e := range events {
+			if !TimelineEventTypes[e.GetEvent()] {
+				continue
+			}
+			out = append(out, &IssueEvent{
+				Type:      e.GetEvent(),
+				Actor:     e.GetActor().GetLogin(),
+				Label:     e.GetLabel().GetName(),
+				Assignee:  e.GetAssignee().GetLogin(),
+				CreatedAt: e.GetCreatedAt(),
+			})
+		}
+		if resp.NextPage == 0 {
+			break
+		}
+		opts.Page = resp.NextPage
+	}
+	sort.SliceStable(out, func(i, j int) bool {
+		return out[i].CreatedAt.Before(out[j].CreatedAt)
+	})
+	return out, nil
+}
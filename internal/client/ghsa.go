@@ -26,6 +26,8 @@ type SecurityAdvisory struct {
 	Origin string
 	// A link to a page for the advisory.
 	Permalink string
+	// Links to more information, such as fixes and reports.
+	References []string `json:",omitempty"`
 	// When the advisory was first published.
 	PublishedAt time.Time
 	// When the advisory was last updated; should always be >= PublishedAt.
@@ -76,6 +78,7 @@ type gqlSecurityAdvisory struct {
 	Description     string
 	Origin          string
 	Permalink       githubv4.URI
+	References      []struct{ URL githubv4.URI }
 	PublishedAt     time.Time
 	UpdatedAt       time.Time
 	Vulnerabilities struct {
@@ -115,6 +118,9 @@ func (sa *gqlSecurityAdvisory) securityAdvisory() (*SecurityAdvisory, error) {
 		PublishedAt: sa.PublishedAt,
 		UpdatedAt:   sa.UpdatedAt,
 	}
+	for _, r := range sa.References {
+		s.References = append(s.References, r.URL.URL.String())
+	}
 	for _, v := range sa.Vulnerabilities.Nodes {
 		s.Vulns = append(s.Vulns, &Vuln{
 			Package:                v.Package.Name,
@@ -0,0 +1,51 @@
+// Copyright 2022 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package client
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/google/go-github/v41/github"
+	"github.com/julieqiu/derrors"
+)
+
+// AddLabels adds labels to an issue. Labels the issue already has are left
+// alone.
+//
+// GitHub API docs: https://docs.github.com/en/free-pro-team@latest/rest/reference/issues/#add-labels-to-an-issue
+func (c *Client) AddLabels(ctx context.Context, number int, labels ...string) (err error) {
+	defer derrors.Wrap(&err, "AddLabels(ctx, %d, %q)", number, labels)
+	_, _, err = c.client.Issues.AddLabelsToIssue(ctx, c.owner, c.repo, number, labels)
+	return err
+}
+
+// RemoveLabel removes a label from an issue. It is not an error if the issue
+// does not have the label.
+//
+// GitHub API docs: https://docs.github.com/en/free-pro-team@latest/rest/reference/issues/#remove-a-label-from-an-issue
+func (c *Client) RemoveLabel(ctx context.Context, number int, label string) (err error) {
+	defer derrors.Wrap(&err, "RemoveLabel(ctx, %d, %q)", number, label)
+	_, err = c.client.Issues.RemoveLabelForIssue(ctx, c.owner, c.repo, number, label)
+	var rerr *github.ErrorResponse
+	if errors.As(err, &rerr) && rerr.Response.StatusCode == http.StatusNotFound {
+		return nil
+	}
+	return err
+}
+
+// ReplaceLabels replaces all the labels of an issue. If labels is empty, all
+// labels are removed.
+//
+// GitHub API docs: https://docs.github.com/en/free-pro-team@latest/rest/reference/issues/#set-labels-for-an-issue
+func (c *Client) ReplaceLabels(ctx context.Context, number int, labels []string) (err error) {
+	defer derrors.Wrap(&err, "ReplaceLabels(ctx, %d, %q)", number, labels)
+	if labels == nil {
+		labels = []string{}
+	}
+	_, _, err = c.client.Issues.ReplaceLabelsForIssue(ctx, c.owner, c.repo, number, labels)
+	return err
+}
@@ -0,0 +1,69 @@
+// Copyright 2022 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package client
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// An Option configures a Client created by New.
+type Option func(*options)
+
+type options struct {
+	restURL    string
+	graphQLURL string
+	httpClient *http.Client
+	userAgent  string
+	parser     *IssueParser
+	labels     LabelNames
+}
+
+// WithRESTURL sets the base URL of the REST API. For GitHub Enterprise it is
+// usually of the form "https://HOST/api/v3/". The default is
+// "https://api.github.com/".
+func WithRESTURL(u string) Option {
+	return func(o *options) { o.restURL = u }
+}
+
+// WithGraphQLURL sets the URL of the GraphQL API. For GitHub Enterprise it is
+// usually of the form "https://HOST/api/graphql". The default is
+// "https://api.github.com/graphql".
+func WithGraphQLURL(u string) Option {
+	return func(o *options) { o.graphQLURL = u }
+}
+
+// WithHTTPClient sets the HTTP client used to make requests. The access token
+// and retry logic are layered on top of its transport.
+func WithHTTPClient(hc *http.Client) Option {
+	return func(o *options) { o.httpClient = hc }
+}
+
+// WithUserAgent sets the User-Agent header sent with every request.
+func WithUserAgent(ua string) Option {
+	return func(o *options) { o.userAgent = ua }
+}
+
+// WithIssueParser sets the parser used for issue titles. The default parser
+// uses DefaultIssueFormats.
+func WithIssueParser(p *IssueParser) Option {
+	return func(o *options) { o.parser = p }
+}
+
+// WithLabelNames sets the names of the labels used to derive fields of
+// issues, such as IsStdLib. Empty names are the defaults; see
+// DefaultLabelNames.
+func WithLabelNames(names LabelNames) Option {
+	return func(o *options) { o.labels = names }
+}
+
+// parseBaseURL parses a REST API base URL, which must end in a slash.
+func parseBaseURL(s string) (*url.URL, error) {
+	if !strings.HasSuffix(s, "/") {
+		s += "/"
+	}
+	return url.Parse(s)
+}
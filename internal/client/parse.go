@@ -0,0 +1,166 @@
+// Copyright 2022 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package client
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+// An IssueFormat describes one way of writing the title of a vulndb issue.
+type IssueFormat struct {
+	// Name identifies the format in error messages.
+	Name string
+	// Title matches titles in this format. It must have a named group
+	// "module" holding the module path, optionally quoted and followed by a
+	// version, and may have a named group "ids" holding the vulnerability IDs.
+	// If there is no "ids" group, or it holds no IDs, the IDs are taken from
+	// the issue body.
+	Title *regexp.Regexp
+}
+
+// DefaultIssueFormats returns the formats used by NewIssueParser when none
+// are given.
+func DefaultIssueFormats() []*IssueFormat {
+	return []*IssueFormat{
+		{
+			// x/vulndb: potential Go vuln in github.com/a/b: CVE-2022-1234, GHSA-xxxx-xxxx-xxxx
+			Name:  "potential",
+			Title: regexp.MustCompile(`^x\/vulndb: potential Go vuln in (?P<module>.+): (?P<ids>.*)$`),
+		},
+		{
+			// x/vulndb: potential Go vuln in github.com/a/b
+			// (IDs are only in the body.)
+			Name:  "potential-no-id",
+			Title: regexp.MustCompile(`^x\/vulndb: potential Go vuln in (?P<module>[^:]+)$`),
+		},
+		{
+			// x/vulndb: CVE-2021-1234 in github.com/a/b
+			Name:  "legacy",
+			Title: regexp.MustCompile(`^x\/vulndb: (?P<ids>(?:(?:CVE|GHSA|GO)-\S+(?:,\s*|\s+))+)in (?P<module>.+)$`),
+		},
+	}
+}
+
+// An IssueParser parses the titles of vulndb issues using a list of formats,
+// which are tried in order.
+// It is safe for concurrent use.
+type IssueParser struct {
+	mu      sync.Mutex
+	formats []*IssueFormat
+}
+
+// NewIssueParser returns an IssueParser for the given formats. If none are
+// given, DefaultIssueFormats is used.
+func NewIssueParser(formats ...*IssueFormat) (*IssueParser, error) {
+	if len(formats) == 0 {
+		formats = DefaultIssueFormats()
+	}
+	p := &IssueParser{}
+	for _, f := range formats {
+		if err := p.Register(f); err != nil {
+			return nil, err
+		}
+	}
+	return p, nil
+}
+
+// Register adds a format, which is tried after the existing ones.
+func (p *IssueParser) Register(f *IssueFormat) error {
+	if f.Title == nil || f.Title.SubexpIndex("module") < 0 {
+		return fmt.Errorf("issue format %q: title regexp has no \"module\" group", f.Name)
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.formats = append(p.formats, f)
+	return nil
+}
+
+// A ParsedIssue holds the information extracted from an issue title and body.
+type ParsedIssue struct {
+	// Format is the name of the format that matched.
+	Format string
+	// ModulePath is the affected module path.
+	ModulePath string
+	// ModuleVersion is the version given with the module path, if any.
+	ModuleVersion string
+	// IDs are the distinct CVE, GHSA and GO IDs, in order of appearance.
+	IDs []string
+}
+
+// idRegexp matches CVE, GHSA and Go vulnerability IDs.
+var idRegexp = regexp.MustCompile(`\b(?:CVE-\d{4}-\d{4,}|GHSA(?:-[2-9cfghjmpqrvwx]{4}){3}|GO-\d{4}-\d{4,})\b`)
+
+// Parse parses an issue title, falling back to the body for the IDs if the
+// title does not contain any.
+func (p *IssueParser) Parse(title, body string) (*ParsedIssue, error) {
+	p.mu.Lock()
+	formats := p.formats
+	p.mu.Unlock()
+
+	for _, f := range formats {
+		m := f.Title.FindStringSubmatch(title)
+		if m == nil {
+			continue
+		}
+		mp, version := parseModule(m[f.Title.SubexpIndex("module")])
+		if mp == "" {
+			return nil, errors.New("title has no module path")
+		}
+		pi := &ParsedIssue{Format: f.Name, ModulePath: mp, ModuleVersion: version}
+		if k := f.Title.SubexpIndex("ids"); k >= 0 {
+			pi.IDs = findIDs(m[k])
+		}
+		if len(pi.IDs) == 0 {
+			pi.IDs = findIDs(body)
+		}
+		if len(pi.IDs) == 0 {
+			return nil, errors.New("no CVE, GHSA or GO ID in title or body")
+		}
+		return pi, nil
+	}
+	var names []string
+	for _, f := range formats {
+		names = append(names, f.Name)
+	}
+	return nil, fmt.Errorf("title does not match any known format (%s)", strings.Join(names, ", "))
+}
+
+// parseModule splits a module as written in a title, such as
+// `"github.com/a/b" v1.2.3` or github.com/a/b@v1.2.3, into a path and
+// version.
+func parseModule(s string) (path, version string) {
+	s = strings.TrimSpace(s)
+	if strings.HasPrefix(s, `"`) {
+		if end := strings.Index(s[1:], `"`); end >= 0 {
+			path, version = s[1:end+1], strings.TrimSpace(s[end+2:])
+			return path, strings.TrimPrefix(version, "@")
+		}
+		s = s[1:]
+	}
+	if k := strings.IndexAny(s, "@ "); k >= 0 {
+		path, version = s[:k], strings.TrimSpace(s[k+1:])
+	} else {
+		path = s
+	}
+	return strings.Trim(path, `"`), strings.Trim(version, `"`)
+}
+
+// findIDs returns the distinct vulnerability IDs in s, in order of
+// appearance.
+func findIDs(s string) []string {
+	var ids []string
+	seen := map[string]bool{}
+	for _, id := range idRegexp.FindAllString(s, -1) {
+		if !seen[id] {
+			seen[id] = true
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
@@ -0,0 +1,151 @@
+// Copyright 2022 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package client
+
+import (
+	"regexp"
+	"strings"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	p, err := NewIssueParser()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, test := range []struct {
+		name, title, body string
+		want              *ParsedIssue
+	}{
+		{
+			name:  "potential",
+			title: "x/vulndb: potential Go vuln in github.com/a/b: CVE-2022-1234",
+			want:  &ParsedIssue{Format: "potential", ModulePath: "github.com/a/b", IDs: []string{"CVE-2022-1234"}},
+		},
+		{
+			name:  "potential-no-id",
+			title: "x/vulndb: potential Go vuln in github.com/a/b",
+			body:  "See GHSA-8r3f-844c-mc37.",
+			want:  &ParsedIssue{Format: "potential-no-id", ModulePath: "github.com/a/b", IDs: []string{"GHSA-8r3f-844c-mc37"}},
+		},
+		{
+			name:  "legacy",
+			title: "x/vulndb: CVE-2021-1234 in github.com/a/b",
+			want:  &ParsedIssue{Format: "legacy", ModulePath: "github.com/a/b", IDs: []string{"CVE-2021-1234"}},
+		},
+		{
+			name:  "legacy several IDs",
+			title: "x/vulndb: CVE-2021-1234, GHSA-8r3f-844c-mc37 GO-2021-0001 in github.com/a/b",
+			want: &ParsedIssue{Format: "legacy", ModulePath: "github.com/a/b",
+				IDs: []string{"CVE-2021-1234", "GHSA-8r3f-844c-mc37", "GO-2021-0001"}},
+		},
+		{
+			name:  "quoted module and version",
+			title: `x/vulndb: potential Go vuln in "github.com/a/b" v1.2.3: CVE-2022-1234`,
+			want: &ParsedIssue{Format: "potential", ModulePath: "github.com/a/b", ModuleVersion: "v1.2.3",
+				IDs: []string{"CVE-2022-1234"}},
+		},
+		{
+			name:  "path at version",
+			title: "x/vulndb: potential Go vuln in github.com/a/b@v1.2.3: CVE-2022-1234",
+			want: &ParsedIssue{Format: "potential", ModulePath: "github.com/a/b", ModuleVersion: "v1.2.3",
+				IDs: []string{"CVE-2022-1234"}},
+		},
+		{
+			name:  "several IDs deduplicated in order",
+			title: "x/vulndb: potential Go vuln in github.com/a/b: GHSA-8r3f-844c-mc37, CVE-2022-1234 GO-2022-0001, CVE-2022-1234",
+			want: &ParsedIssue{Format: "potential", ModulePath: "github.com/a/b",
+				IDs: []string{"GHSA-8r3f-844c-mc37", "CVE-2022-1234", "GO-2022-0001"}},
+		},
+		{
+			name:  "IDs from body",
+			title: "x/vulndb: potential Go vuln in github.com/a/b: see body",
+			body:  "CVE-2022-1234 and CVE-2022-5678, also CVE-2022-1234.",
+			want: &ParsedIssue{Format: "potential", ModulePath: "github.com/a/b",
+				IDs: []string{"CVE-2022-1234", "CVE-2022-5678"}},
+		},
+		{
+			name:  "IDs in title win over body",
+			title: "x/vulndb: potential Go vuln in github.com/a/b: CVE-2022-1234",
+			body:  "CVE-2022-5678",
+			want:  &ParsedIssue{Format: "potential", ModulePath: "github.com/a/b", IDs: []string{"CVE-2022-1234"}},
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := p.Parse(test.title, test.body)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got.Format != test.want.Format || got.ModulePath != test.want.ModulePath ||
+				got.ModuleVersion != test.want.ModuleVersion ||
+				strings.Join(got.IDs, " ") != strings.Join(test.want.IDs, " ") {
+				t.Errorf("got %+v, want %+v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	p, err := NewIssueParser()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, test := range []struct {
+		name, title, body string
+		want              string
+	}{
+		{
+			name:  "no module",
+			title: `x/vulndb: potential Go vuln in "": CVE-2022-1234`,
+			want:  "title has no module path",
+		},
+		{
+			name:  "no ID",
+			title: "x/vulndb: potential Go vuln in github.com/a/b",
+			body:  "Nothing to see here.",
+			want:  "no CVE, GHSA or GO ID in title or body",
+		},
+		{
+			name:  "no format",
+			title: "data/reports: add GO-2022-0001",
+			want:  "title does not match any known format (potential, potential-no-id, legacy)",
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := p.Parse(test.title, test.body)
+			if err == nil || err.Error() != test.want {
+				t.Errorf("got error %v, want %q", err, test.want)
+			}
+		})
+	}
+}
+
+func TestRegister(t *testing.T) {
+	p, err := NewIssueParser()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := p.Register(&IssueFormat{Name: "bad", Title: regexp.MustCompile(`^vuln in (?P<mod>\S+)$`)}); err == nil {
+		t.Error("registered a format with no module group")
+	}
+	if err := p.Register(&IssueFormat{Name: "nil"}); err == nil {
+		t.Error("registered a format with no title regexp")
+	}
+	if _, err := NewIssueParser(&IssueFormat{Name: "bad", Title: regexp.MustCompile(`^(.*)$`)}); err == nil {
+		t.Error("NewIssueParser accepted a format with no module group")
+	}
+
+	// A registered format is tried after the others.
+	if err := p.Register(&IssueFormat{Name: "short", Title: regexp.MustCompile(`^vuln in (?P<module>\S+)$`)}); err != nil {
+		t.Fatal(err)
+	}
+	got, err := p.Parse("vuln in github.com/a/b", "CVE-2022-1234")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Format != "short" || got.ModulePath != "github.com/a/b" {
+		t.Errorf("got %+v, want format short for github.com/a/b", got)
+	}
+}
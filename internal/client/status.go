@@ -0,0 +1,119 @@
+// Copyright 2022 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package client
+
+import (
+	"fmt"
+	"strings"
+)
+
+// A Status is a stage in the lifecycle of a vulndb issue.
+//
+// A new issue is triaged by labeling or assigning it. It then either needs a
+// report, is excluded from the database or is closed as a duplicate. An issue
+// that needs a report is in review while a pull request that mentions it is
+// open, and is published once its report is in the database.
+type Status string
+
+const (
+	StatusNew            Status = "new"
+	StatusTriaged        Status = "triaged"
+	StatusNeedsReport    Status = "needs-report"
+	StatusReportInReview Status = "report-in-review"
+	StatusPublished      Status = "published"
+	StatusExcluded       Status = "excluded"
+	StatusDuplicate      Status = "duplicate"
+)
+
+// Statuses are all the statuses, in lifecycle order.
+var Statuses = []Status{
+	StatusNew,
+	StatusTriaged,
+	StatusNeedsReport,
+	StatusReportInReview,
+	StatusPublished,
+	StatusExcluded,
+	StatusDuplicate,
+}
+
+// ParseStatus returns the Status with the given name.
+func ParseStatus(name string) (Status, error) {
+	for _, s := range Statuses {
+		if string(s) == name {
+			return s, nil
+		}
+	}
+	var names []string
+	for _, s := range Statuses {
+		names = append(names, string(s))
+	}
+	return "", fmt.Errorf("unknown status %q (want one of %s)", name, strings.Join(names, ", "))
+}
+
+// StatusUsing derives the issue's status from its state, its labels named
+// by names, the pull requests that mention it and whether it has a vulndb
+// report. The first of these that holds decides the status:
+//
+//   - it has a report: published
+//   - it is labeled names.Duplicate: duplicate
+//   - it is labeled names.NotGoVuln: excluded
+//   - an open pull request mentions it: report-in-review
+//   - it is labeled names.NeedsReport: needs-report
+//   - it is closed: excluded
+//   - it has a label or an assignee: triaged
+//   - otherwise: new
+//
+// The issue's HasReport and PullRequests fields must be set.
+func (i *Issue) StatusUsing(names LabelNames) Status {
+	switch {
+	case i.HasReport:
+		return StatusPublished
+	case i.Labels[names.Duplicate]:
+		return StatusDuplicate
+	case i.Labels[names.NotGoVuln]:
+		return StatusExcluded
+	case len(i.PullRequests) > 0:
+		return StatusReportInReview
+	case i.Labels[names.NeedsReport]:
+		return StatusNeedsReport
+	case !i.Open:
+		return StatusExcluded
+	case len(i.Labels) > 0 || len(i.Assignees) > 0:
+		return StatusTriaged
+	default:
+		return StatusNew
+	}
+}
+
+// transitions are the allowed changes of status. An issue may also keep its
+// status.
+var transitions = map[Status][]Status{
+	StatusNew:            {StatusTriaged, StatusNeedsReport, StatusReportInReview, StatusPublished, StatusExcluded, StatusDuplicate},
+	StatusTriaged:        {StatusNew, StatusNeedsReport, StatusReportInReview, StatusPublished, StatusExcluded, StatusDuplicate},
+	StatusNeedsReport:    {StatusTriaged, StatusReportInReview, StatusPublished, StatusExcluded, StatusDuplicate},
+	StatusReportInReview: {StatusNeedsReport, StatusPublished, StatusExcluded, StatusDuplicate},
+	StatusPublished:      nil,
+	StatusExcluded:       {StatusNew, StatusTriaged, StatusNeedsReport, StatusDuplicate},
+	StatusDuplicate:      {StatusNew, StatusTriaged, StatusExcluded},
+}
+
+// CheckTransition returns an error if an issue may not go from status from to
+// status to. In particular, a published report is never removed, so a
+// published issue keeps that status.
+func CheckTransition(from, to Status) error {
+	if from == to {
+		return nil
+	}
+	next, ok := transitions[from]
+	if !ok {
+		return fmt.Errorf("unknown status %q", from)
+	}
+	for _, s := range next {
+		if s == to {
+			return nil
+		}
+	}
+	return fmt.Errorf("issue may not go from %s to %s", from, to)
+}
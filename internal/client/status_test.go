@@ -0,0 +1,89 @@
+// Copyright 2022 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package client
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestStatusUsing(t *testing.T) {
+	names := LabelNames{
+		NotGoVuln:   "not-go",
+		NeedsReport: "needs",
+		Duplicate:   "dup",
+	}.WithDefaults()
+	issue := func(open bool, labels ...string) *Issue {
+		i := &Issue{Open: open, Labels: map[string]bool{}}
+		for _, l := range labels {
+			i.Labels[l] = true
+		}
+		return i
+	}
+	withReport := func(i *Issue) *Issue { i.HasReport = true; return i }
+	withPR := func(i *Issue) *Issue { i.PullRequests = []int{200}; return i }
+	withAssignee := func(i *Issue) *Issue { i.Assignees = []string{"gopher"}; return i }
+
+	for _, test := range []struct {
+		name  string
+		issue *Issue
+		want  Status
+	}{
+		// Each rule, with the conditions of the later rules also holding.
+		{"report", withReport(withPR(issue(false, "dup", "not-go", "needs"))), StatusPublished},
+		{"duplicate", withPR(issue(false, "dup", "not-go", "needs")), StatusDuplicate},
+		{"not a Go vuln", withPR(issue(false, "not-go", "needs")), StatusExcluded},
+		{"open pull request", withPR(issue(false, "needs")), StatusReportInReview},
+		{"needs report", withAssignee(issue(false, "needs")), StatusNeedsReport},
+		{"closed", withAssignee(issue(false, "other")), StatusExcluded},
+		{"labeled", issue(true, "other"), StatusTriaged},
+		{"assigned", withAssignee(issue(true)), StatusTriaged},
+		{"new", issue(true), StatusNew},
+
+		// The default names mean nothing when others are configured.
+		{"default duplicate name", issue(true, DefaultLabelNames.Duplicate), StatusTriaged},
+		{"default needs report name", issue(true, DefaultLabelNames.NeedsReport), StatusTriaged},
+		{"default not a Go vuln name", issue(true, DefaultLabelNames.NotGoVuln), StatusTriaged},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			if got := test.issue.StatusUsing(names); got != test.want {
+				t.Errorf("got %s, want %s", got, test.want)
+			}
+		})
+	}
+}
+
+func TestCheckTransition(t *testing.T) {
+	// allowed lists, for each status, the statuses it may change to, as
+	// "from: to to ...". An issue may always keep its status.
+	allowed := []string{
+		"new: triaged needs-report report-in-review published excluded duplicate",
+		"triaged: new needs-report report-in-review published excluded duplicate",
+		"needs-report: triaged report-in-review published excluded duplicate",
+		"report-in-review: needs-report published excluded duplicate",
+		"published:",
+		"excluded: new triaged needs-report duplicate",
+		"duplicate: new triaged excluded",
+	}
+	ok := map[[2]Status]bool{}
+	for _, line := range allowed {
+		from, tos, _ := strings.Cut(line, ":")
+		ok[[2]Status{Status(from), Status(from)}] = true
+		for _, to := range strings.Fields(tos) {
+			ok[[2]Status{Status(from), Status(to)}] = true
+		}
+	}
+	for _, from := range Statuses {
+		for _, to := range Statuses {
+			err := CheckTransition(from, to)
+			if want := ok[[2]Status{from, to}]; (err == nil) != want {
+				t.Errorf("CheckTransition(%s, %s) = %v, want allowed: %t", from, to, err, want)
+			}
+		}
+	}
+	if err := CheckTransition("bogus", StatusNew); err == nil {
+		t.Error("CheckTransition accepted an unknown status")
+	}
+}
@@ -0,0 +1,265 @@
+// Copyright 2022 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package client
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"math/rand"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// A RateLimit describes the API budget that GitHub reports for one resource,
+// such as "core" for the REST API or "graphql".
+type RateLimit struct {
+	Resource string
+	// Limit is the number of requests allowed per window.
+	Limit int
+	// Remaining is the number of requests left in the current window.
+	Remaining int
+	// Reset is when the current window ends.
+	Reset time.Time
+	// Retries is the number of requests for this resource that were retried.
+	Retries int
+	// UpdatedAt is when the values above were last reported by GitHub.
+	UpdatedAt time.Time
+}
+
+const (
+	// maxRetries is the number of times a request is retried.
+	maxRetries = 5
+	// maxBackoff is the longest we wait between attempts when GitHub
+	// does not tell us how long to wait.
+	maxBackoff = time.Minute
+	// maxWait is the longest we are willing to wait for a rate limit
+	// to reset. Longer waits give up and return the response.
+	maxWait = 15 * time.Minute
+)
+
+// A transport is an http.RoundTripper that records the rate limit headers
+// returned by GitHub, and retries idempotent requests that fail because of
+// rate limiting, server errors or network errors.
+type transport struct {
+	base http.RoundTripper
+	// userAgent, if set, is sent with every request.
+	userAgent string
+	// sleep waits for d, or until ctx is done. Tests replace it.
+	sleep func(ctx context.Context, d time.Duration) error
+
+	mu     sync.Mutex
+	limits map[string]*RateLimit
+}
+
+func newTransport(base http.RoundTripper) *transport {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return &transport{base: base, sleep: sleep, limits: map[string]*RateLimit{}}
+}
+
+// RoundTrip implements http.RoundTripper.
+func (t *transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	idempotent := isIdempotent(req)
+	for attempt := 0; ; attempt++ {
+		req2 := req
+		if attempt > 0 || t.userAgent != "" {
+			var err error
+			if req2, err = rewind(req); err != nil {
+				return nil, err
+			}
+		}
+		if t.userAgent != "" {
+			req2.Header.Set("User-Agent", t.userAgent)
+		}
+		resp, err := t.base.RoundTrip(req2)
+		if err == nil {
+			t.record(req, resp)
+		}
+		if !idempotent || attempt == maxRetries || req.Context().Err() != nil {
+			return resp, err
+		}
+		wait, retry := retryAfter(resp, err, attempt)
+		if !retry || wait > maxWait {
+			return resp, err
+		}
+		if resp != nil {
+			// Drain the body so the connection can be reused.
+			io.Copy(io.Discard, resp.Body)
+			resp.Body.Close()
+		}
+		t.countRetry(resourceOf(req, resp))
+		if err := t.sleep(req.Context(), wait); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// retryAfter reports whether a request that produced resp and err should be
+// retried, and how long to wait before doing so. Only network errors, rate
+// limit errors (403 and 429) and server errors (5xx) are retried.
+func retryAfter(resp *http.Response, err error, attempt int) (time.Duration, bool) {
+	if err != nil {
+		return backoff(attempt), true
+	}
+	code := resp.StatusCode
+	if code != http.StatusForbidden && code != http.StatusTooManyRequests && code < 500 {
+		return 0, false
+	}
+	if s := resp.Header.Get("Retry-After"); s != "" {
+		if secs, err := strconv.Atoi(s); err == nil {
+			return time.Duration(secs) * time.Second, true
+		}
+	}
+	if code >= 500 {
+		return backoff(attempt), true
+	}
+	if resp.Header.Get("X-RateLimit-Remaining") == "0" {
+		if reset, ok := parseUnix(resp.Header.Get("X-RateLimit-Reset")); ok {
+			return time.Until(reset) + time.Second, true
+		}
+	}
+	if code == http.StatusTooManyRequests {
+		return backoff(attempt), true
+	}
+	// Other 403s are permission errors.
+	return 0, false
+}
+
+// backoff returns an exponentially increasing wait with full jitter.
+func backoff(attempt int) time.Duration {
+	d := time.Second << attempt
+	if d > maxBackoff {
+		d = maxBackoff
+	}
+	return time.Duration(rand.Int63n(int64(d)))
+}
+
+func sleep(ctx context.Context, d time.Duration) error {
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-t.C:
+		return nil
+	}
+}
+
+// isIdempotent reports whether req can safely be sent more than once.
+// GraphQL queries are sent with POST, but only mutations change anything.
+func isIdempotent(req *http.Request) bool {
+	switch req.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodPut, http.MethodDelete:
+		return true
+	case http.MethodPost:
+		if !strings.HasSuffix(req.URL.Path, "/graphql") || req.GetBody == nil {
+			return false
+		}
+		body, err := req.GetBody()
+		if err != nil {
+			return false
+		}
+		defer body.Close()
+		data, err := io.ReadAll(body)
+		if err != nil {
+			return false
+		}
+		return !bytes.Contains(data, []byte(`"query":"mutation`))
+	}
+	return false
+}
+
+// rewind returns a copy of req with a fresh body.
+func rewind(req *http.Request) (*http.Request, error) {
+	req2 := req.Clone(req.Context())
+	if req.Body != nil && req.GetBody != nil {
+		body, err := req.GetBody()
+		if err != nil {
+			return nil, err
+		}
+		req2.Body = body
+	}
+	return req2, nil
+}
+
+// resourceOf returns the rate limit resource that req counts against.
+func resourceOf(req *http.Request, resp *http.Response) string {
+	if resp != nil {
+		if r := resp.Header.Get("X-RateLimit-Resource"); r != "" {
+			return r
+		}
+	}
+	if strings.HasSuffix(req.URL.Path, "/graphql") {
+		return "graphql"
+	}
+	return "core"
+}
+
+// record saves the rate limit information from resp.
+func (t *transport) record(req *http.Request, resp *http.Response) {
+	limit, err := strconv.Atoi(resp.Header.Get("X-RateLimit-Limit"))
+	if err != nil {
+		return
+	}
+	remaining, err := strconv.Atoi(resp.Header.Get("X-RateLimit-Remaining"))
+	if err != nil {
+		return
+	}
+	reset, _ := parseUnix(resp.Header.Get("X-RateLimit-Reset"))
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	rl := t.limit(resourceOf(req, resp))
+	rl.Limit = limit
+	rl.Remaining = remaining
+	rl.Reset = reset
+	rl.UpdatedAt = time.Now()
+}
+
+func (t *transport) countRetry(resource string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.limit(resource).Retries++
+}
+
+// limit returns the RateLimit for resource, creating it if necessary.
+// t.mu must be held.
+func (t *transport) limit(resource string) *RateLimit {
+	rl, ok := t.limits[resource]
+	if !ok {
+		rl = &RateLimit{Resource: resource}
+		t.limits[resource] = rl
+	}
+	return rl
+}
+
+// rateLimits returns copies of the rate limits seen so far, sorted by
+// resource.
+func (t *transport) rateLimits() []RateLimit {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	var out []RateLimit
+	for _, rl := range t.limits {
+		out = append(out, *rl)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		return out[i].Resource < out[j].Resource
+	})
+	return out
+}
+
+func parseUnix(s string) (time.Time, bool) {
+	secs, err := strconv.ParseInt(s, 10, 64)
+	if err != nil {
+		return time.Time{}, false
+	}
+	return time.Unix(secs, 0), true
+}
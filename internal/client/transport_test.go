@@ -0,0 +1,173 @@
+// Copyright 2022 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package client
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+)
+
+// recordSleeps makes the transport of c record its waits instead of
+// sleeping, and returns them.
+func recordSleeps(c *Client) *[]time.Duration {
+	var waits []time.Duration
+	c.transport.sleep = func(_ context.Context, d time.Duration) error {
+		waits = append(waits, d)
+		return nil
+	}
+	return &waits
+}
+
+func TestRetry(t *testing.T) {
+	reset := func(d time.Duration) string {
+		return strconv.FormatInt(time.Now().Add(d).Unix(), 10)
+	}
+	for _, test := range []struct {
+		name     string
+		failures int
+		status   int
+		header   http.Header
+		// wantRequests is the number of requests the server should see.
+		wantRequests int
+		wantErr      bool
+		// minWait and maxWait bound each wait between attempts.
+		minWait, maxWait time.Duration
+	}{
+		{
+			name:         "server errors",
+			failures:     2,
+			status:       http.StatusBadGateway,
+			wantRequests: 3,
+			maxWait:      2 * time.Second,
+		},
+		{
+			name:         "too many server errors",
+			failures:     maxRetries + 1,
+			status:       http.StatusInternalServerError,
+			wantRequests: maxRetries + 1,
+			wantErr:      true,
+			maxWait:      maxBackoff,
+		},
+		{
+			name:         "Retry-After",
+			failures:     1,
+			status:       http.StatusTooManyRequests,
+			header:       http.Header{"Retry-After": {"7"}},
+			wantRequests: 2,
+			minWait:      7 * time.Second,
+			maxWait:      7 * time.Second,
+		},
+		{
+			name:         "rate limit reset",
+			failures:     1,
+			status:       http.StatusForbidden,
+			header:       http.Header{"X-RateLimit-Remaining": {"0"}, "X-RateLimit-Reset": {reset(30 * time.Second)}},
+			wantRequests: 2,
+			minWait:      29 * time.Second,
+			maxWait:      32 * time.Second,
+		},
+		{
+			name:         "rate limit reset too late",
+			failures:     1,
+			status:       http.StatusForbidden,
+			header:       http.Header{"X-RateLimit-Remaining": {"0"}, "X-RateLimit-Reset": {reset(maxWait + time.Hour)}},
+			wantRequests: 1,
+			wantErr:      true,
+		},
+		{
+			name:         "permission error",
+			failures:     1,
+			status:       http.StatusForbidden,
+			wantRequests: 1,
+			wantErr:      true,
+		},
+		{
+			name:         "not found with Retry-After",
+			failures:     1,
+			status:       http.StatusNotFound,
+			header:       http.Header{"Retry-After": {"1"}},
+			wantRequests: 1,
+			wantErr:      true,
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			c, srv := newTestClient(t)
+			waits := recordSleeps(c)
+			srv.Fail(test.failures, test.status, test.header)
+			_, err := c.ListIssueEvents(context.Background(), 140)
+			if (err != nil) != test.wantErr {
+				t.Fatalf("got error %v, want error: %t", err, test.wantErr)
+			}
+			if got := srv.Requests(); got != test.wantRequests {
+				t.Errorf("server got %d requests, want %d", got, test.wantRequests)
+			}
+			if got, want := len(*waits), test.wantRequests-1; got != want {
+				t.Errorf("waited %d times, want %d", got, want)
+			}
+			for _, w := range *waits {
+				if w < test.minWait || w > test.maxWait {
+					t.Errorf("waited %s, want between %s and %s", w, test.minWait, test.maxWait)
+				}
+			}
+			var retries int
+			for _, rl := range c.RateLimits() {
+				if rl.Resource == "core" {
+					retries = rl.Retries
+				}
+			}
+			if retries != len(*waits) {
+				t.Errorf("core Retries = %d, want %d", retries, len(*waits))
+			}
+		})
+	}
+}
+
+func TestRetryOnlyIdempotent(t *testing.T) {
+	ctx := context.Background()
+
+	// A POST that creates something is not retried.
+	c, srv := newTestClient(t)
+	recordSleeps(c)
+	srv.Fail(1, http.StatusInternalServerError, nil)
+	if err := c.AddComment(ctx, 140, "hello"); err == nil {
+		t.Error("AddComment succeeded, want the injected error")
+	}
+	if got := srv.Requests(); got != 1 {
+		t.Errorf("AddComment: server got %d requests, want 1", got)
+	}
+
+	// A GraphQL query is sent with POST, but is retried.
+	c, srv = newTestClient(t)
+	recordSleeps(c)
+	srv.Fail(1, http.StatusBadGateway, nil)
+	if _, err := c.ListGHSAs(ctx, time.Time{}); err != nil {
+		t.Fatal(err)
+	}
+	if got := srv.Requests(); got != 2 {
+		t.Errorf("ListGHSAs: server got %d requests, want 2", got)
+	}
+}
+
+func TestRetryAfterStatus(t *testing.T) {
+	for _, test := range []struct {
+		status int
+		want   bool
+	}{
+		{http.StatusOK, false},
+		{http.StatusNotModified, false},
+		{http.StatusNotFound, false},
+		{http.StatusForbidden, true},
+		{http.StatusTooManyRequests, true},
+		{http.StatusServiceUnavailable, true},
+	} {
+		resp := &http.Response{StatusCode: test.status, Header: http.Header{"Retry-After": {"1"}}}
+		if _, got := retryAfter(resp, nil, 0); got != test.want {
+			t.Errorf("%d with Retry-After: retry = %t, want %t", test.status, got, test.want)
+		}
+	}
+}
@@ -15,6 +15,8 @@ func New() *Client {
 	// Instantiate default collector
 	c := colly.NewCollector()
 	c.SetRequestTimeout(60 * time.Second)
+	// The release notes page is visited on every refresh.
+	c.AllowURLRevisit = true
 	return &Client{colly: c}
 }
 
@@ -25,9 +27,13 @@ type ReleaseNote struct {
 
 const releaseNotesURL = "https://go.dev/doc/devel/release"
 
-func (c *Client) ReleaseNotes() []*ReleaseNote {
+// ReleaseNotes returns the notes for each Go release. The description is only
+// set for releases that mention security fixes.
+func (c *Client) ReleaseNotes() ([]*ReleaseNote, error) {
 	var notes []*ReleaseNote
-	c.colly.OnHTML("p", func(e *colly.HTMLElement) {
+	// Use a clone so that callbacks from earlier calls are not run again.
+	col := c.colly.Clone()
+	col.OnHTML("p", func(e *colly.HTMLElement) {
 		id := e.Attr("id")
 		if id == "" {
 			return
@@ -41,6 +47,8 @@ func (c *Client) ReleaseNotes() []*ReleaseNote {
 		}
 		notes = append(notes, n)
 	})
-	c.colly.Visit(releaseNotesURL)
-	return notes
+	if err := col.Visit(releaseNotesURL); err != nil {
+		return nil, err
+	}
+	return notes, nil
 }
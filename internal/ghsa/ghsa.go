@@ -11,6 +11,7 @@ import (
 	"time"
 
 	"github.com/shurcooL/githubv4"
+	"golang.org/x/oauth2"
 )
 
 // A SecurityAdvisory represents a GitHub security advisory.
@@ -246,3 +247,8 @@ func isCVE(ids []Identifier) bool {
 	}
 	return false
 }
+
+func newGitHubClient(ctx context.Context, accessToken string) *githubv4.Client {
+	ts := oauth2.StaticTokenSource(&oauth2.Token{AccessToken: accessToken})
+	return githubv4.NewClient(oauth2.NewClient(ctx, ts))
+}
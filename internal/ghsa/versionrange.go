@@ -0,0 +1,288 @@
+// Copyright 2022 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package ghsa
+
+import (
+	"fmt"
+	"strings"
+
+	"golang.org/x/mod/semver"
+	"golang.org/x/vuln/osv"
+)
+
+// An Op is a comparison operator in a vulnerable version range.
+type Op string
+
+const (
+	OpEQ Op = "="
+	OpLT Op = "<"
+	OpLE Op = "<="
+	OpGT Op = ">"
+	OpGE Op = ">="
+)
+
+// ops are the operators, longest first so that "<=" is not read as "<".
+var ops = []Op{OpLE, OpGE, OpEQ, OpLT, OpGT}
+
+// A Constraint is one clause of a vulnerable version range, such as
+// ">= v1.0.3".
+type Constraint struct {
+	Op Op
+	// Version is a valid Go semantic version, with the "v" prefix.
+	Version string
+}
+
+func (c Constraint) String() string {
+	return string(c.Op) + " " + c.Version
+}
+
+// A VersionRange is a parsed GitHub vulnerable version range. A version is
+// in the range if it satisfies every constraint.
+type VersionRange []Constraint
+
+func (r VersionRange) String() string {
+	var cs []string
+	for _, c := range r {
+		cs = append(cs, c.String())
+	}
+	return strings.Join(cs, ", ")
+}
+
+// ParseVersionRange parses a vulnerable version range in GitHub's grammar:
+// a comma-separated list of clauses, each an operator (=, <, <=, > or >=)
+// and a version, such as ">= 1.0.3, < 1.2.0". A clause with no operator
+// means "=". Versions are normalized as by NormalizeVersion.
+//
+// The range must describe a single interval: it may have an "=" clause on
+// its own, or at most one lower bound (> or >=) and one upper bound (< or
+// <=), and the lower bound must be below the upper bound.
+func ParseVersionRange(s string) (_ VersionRange, err error) {
+	defer func() {
+		if err != nil {
+			err = fmt.Errorf("version range %q: %v", s, err)
+		}
+	}()
+	if strings.TrimSpace(s) == "" {
+		return nil, fmt.Errorf("empty")
+	}
+	var r VersionRange
+	for _, clause := range strings.Split(s, ",") {
+		clause = strings.TrimSpace(clause)
+		if clause == "" {
+			return nil, fmt.Errorf("empty clause")
+		}
+		c := Constraint{Op: OpEQ}
+		for _, op := range ops {
+			if strings.HasPrefix(clause, string(op)) {
+				c.Op = op
+				clause = strings.TrimSpace(clause[len(op):])
+				break
+			}
+		}
+		if c.Version, err = NormalizeVersion(clause); err != nil {
+			return nil, err
+		}
+		r = append(r, c)
+	}
+	if err := r.check(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// check reports whether r describes a single non-empty interval.
+func (r VersionRange) check() error {
+	lo, hi, eq := r.bounds()
+	n := 0
+	for _, c := range []*Constraint{lo, hi, eq} {
+		if c != nil {
+			n++
+		}
+	}
+	if n != len(r) {
+		return fmt.Errorf("more than one lower or upper bound")
+	}
+	if eq != nil && len(r) > 1 {
+		return fmt.Errorf("= must be the only clause")
+	}
+	if lo != nil && hi != nil {
+		cmp := semver.Compare(lo.Version, hi.Version)
+		if cmp > 0 || (cmp == 0 && (lo.Op == OpGT || hi.Op == OpLT)) {
+			return fmt.Errorf("no version satisfies it")
+		}
+	}
+	return nil
+}
+
+// bounds returns the lower bound, upper bound and equality constraints of
+// r. If r has more than one of a kind, the last is returned.
+func (r VersionRange) bounds() (lo, hi, eq *Constraint) {
+	for k := range r {
+		c := &r[k]
+		switch c.Op {
+		case OpGT, OpGE:
+			lo = c
+		case OpLT, OpLE:
+			hi = c
+		case OpEQ:
+			eq = c
+		}
+	}
+	return lo, hi, eq
+}
+
+// Contains reports whether the version v is in the range.
+func (r VersionRange) Contains(v string) bool {
+	v, err := NormalizeVersion(v)
+	if err != nil {
+		return false
+	}
+	for _, c := range r {
+		cmp := semver.Compare(v, c.Version)
+		var ok bool
+		switch c.Op {
+		case OpEQ:
+			ok = cmp == 0
+		case OpLT:
+			ok = cmp < 0
+		case OpLE:
+			ok = cmp <= 0
+		case OpGT:
+			ok = cmp > 0
+		case OpGE:
+			ok = cmp >= 0
+		}
+		if !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// Overlaps reports whether some version is in both r and s. Versions are
+// dense: between any two versions there is a prerelease of the greater.
+func (r VersionRange) Overlaps(s VersionRange) bool {
+	var lo, hi *Constraint
+	for _, c := range append(append(VersionRange(nil), r...), s...) {
+		c := c
+		switch c.Op {
+		case OpEQ:
+			lo = tighter(lo, &Constraint{Op: OpGE, Version: c.Version}, 1)
+			hi = tighter(hi, &Constraint{Op: OpLE, Version: c.Version}, -1)
+		case OpGT, OpGE:
+			lo = tighter(lo, &c, 1)
+		case OpLT, OpLE:
+			hi = tighter(hi, &c, -1)
+		}
+	}
+	if lo == nil || hi == nil {
+		return true
+	}
+	cmp := semver.Compare(lo.Version, hi.Version)
+	return cmp < 0 || (cmp == 0 && lo.Op == OpGE && hi.Op == OpLE)
+}
+
+// tighter returns the tighter of two lower bounds if dir is 1, or of two
+// upper bounds if dir is -1. Either may be nil.
+func tighter(a, b *Constraint, dir int) *Constraint {
+	if a == nil {
+		return b
+	}
+	cmp := semver.Compare(a.Version, b.Version) * dir
+	switch {
+	case cmp > 0:
+		return a
+	case cmp < 0:
+		return b
+	case a.Op == OpGT || a.Op == OpLT:
+		// Strict bounds exclude the version itself.
+		return a
+	default:
+		return b
+	}
+}
+
+// NormalizeVersion returns v as a Go semantic version: with a "v" prefix,
+// and with missing minor and patch numbers filled in, so that "1.2" becomes
+// "v1.2.0". A "go" prefix, as in "go1.19", is also accepted. Build metadata
+// such as "+incompatible" is kept.
+func NormalizeVersion(v string) (string, error) {
+	v = strings.TrimSpace(v)
+	s := strings.TrimPrefix(strings.TrimPrefix(v, "go"), "v")
+	if s == "" {
+		return "", fmt.Errorf("missing version")
+	}
+	sv := "v" + s
+	if !semver.IsValid(sv) {
+		return "", fmt.Errorf("invalid semantic version %q", v)
+	}
+	if semver.Build(sv) == "" {
+		sv = semver.Canonical(sv)
+	}
+	return sv, nil
+}
+
+// OSVRange converts the range to an OSV SEMVER range. OSV versions have no
+// "v" prefix, following the Go vulnerability database.
+//
+// OSV ranges are made of "introduced" and "fixed" events, so some ranges
+// have no exact conversion. An upper bound "<= x" or an "= x" clause is
+// converted using earliestFixed, the advisory's first patched version, if
+// it is above x. Otherwise the range is left open, which errs on the side of
+// reporting too many versions as vulnerable. A lower bound "> x" cannot be
+// converted, since the first version after x is unknown.
+func (r VersionRange) OSVRange(earliestFixed string) (_ osv.AffectsRange, err error) {
+	defer func() {
+		if err != nil {
+			err = fmt.Errorf("converting %q to OSV: %v", r, err)
+		}
+	}()
+	out := osv.AffectsRange{Type: osv.TypeSemver}
+	if err := r.check(); err != nil {
+		return out, err
+	}
+	lo, hi, eq := r.bounds()
+	if eq != nil {
+		lo = &Constraint{Op: OpGE, Version: eq.Version}
+		hi = &Constraint{Op: OpLE, Version: eq.Version}
+	}
+
+	introduced := "0"
+	if lo != nil {
+		if lo.Op == OpGT {
+			return out, fmt.Errorf("cannot express %q", lo)
+		}
+		introduced = osvVersion(lo.Version)
+	}
+	out.Events = append(out.Events, osv.RangeEvent{Introduced: introduced})
+
+	if hi == nil {
+		return out, nil
+	}
+	fixed := ""
+	switch hi.Op {
+	case OpLT:
+		fixed = hi.Version
+	case OpLE:
+		if earliestFixed != "" {
+			f, err := NormalizeVersion(earliestFixed)
+			if err != nil {
+				return out, fmt.Errorf("earliest fixed version: %v", err)
+			}
+			if semver.Compare(f, hi.Version) > 0 {
+				fixed = f
+			}
+		}
+	}
+	if fixed != "" {
+		out.Events = append(out.Events, osv.RangeEvent{Fixed: osvVersion(fixed)})
+	}
+	return out, nil
+}
+
+// osvVersion returns the Go semantic version v without its "v" prefix.
+func osvVersion(v string) string {
+	return strings.TrimPrefix(v, "v")
+}
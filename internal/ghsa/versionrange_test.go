@@ -0,0 +1,219 @@
+// Copyright 2022 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package ghsa
+
+import (
+	"strings"
+	"testing"
+
+	"golang.org/x/vuln/osv"
+)
+
+// events returns the events of r in a short form, such as "i0 f1.2.0".
+func events(r osv.AffectsRange) string {
+	var es []string
+	for _, e := range r.Events {
+		if e.Introduced != "" {
+			es = append(es, "i"+e.Introduced)
+		}
+		if e.Fixed != "" {
+			es = append(es, "f"+e.Fixed)
+		}
+	}
+	return strings.Join(es, " ")
+}
+
+func TestOSVRange(t *testing.T) {
+	for _, test := range []struct {
+		in            string
+		earliestFixed string
+		want          string // events, or an error substring
+		wantErr       bool
+	}{
+		{"= 1.0.0", "1.0.1", "i1.0.0 f1.0.1", false},
+		{"= 1.0.0", "", "i1.0.0", false},
+		{"1.0.0", "1.0.1", "i1.0.0 f1.0.1", false},
+		{"<= 1.2.0", "1.2.1", "i0 f1.2.1", false},
+		{"<= 1.2.0", "1.2.0", "i0", false},
+		{"<= 1.2.0", "", "i0", false},
+		{"< 1.2.0", "", "i0 f1.2.0", false},
+		{">= 1.0.3, < 1.2.0", "1.2.0", "i1.0.3 f1.2.0", false},
+		{"< 1.2.0, >= 1.0.3", "1.2.0", "i1.0.3 f1.2.0", false},
+		{">=1.0.3,<1.2.0", "", "i1.0.3 f1.2.0", false},
+		{">= 1.0", "", "i1.0.0", false},
+		{"<= 1.19.0", "go1.19.1", "i0 f1.19.1", false},
+		{"< 2.0.0+incompatible", "", "i0 f2.0.0+incompatible", false},
+		{"< 1.0.0-rc.1", "", "i0 f1.0.0-rc.1", false},
+		{"< 0.0.0-20220101000000-abcdef123456", "", "i0 f0.0.0-20220101000000-abcdef123456", false},
+		{"> 1.0.0", "", `cannot express "> v1.0.0"`, true},
+		{"> 1.0.0, < 2.0.0", "", `cannot express "> v1.0.0"`, true},
+		{"<= 1.2.0", "bad", "earliest fixed version", true},
+	} {
+		t.Run(test.in+"/"+test.earliestFixed, func(t *testing.T) {
+			r, err := ParseVersionRange(test.in)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := r.OSVRange(test.earliestFixed)
+			if test.wantErr {
+				if err == nil || !strings.Contains(err.Error(), test.want) {
+					t.Errorf("got error %v, want one containing %q", err, test.want)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got.Type != osv.TypeSemver || events(got) != test.want {
+				t.Errorf("got %s %q, want SEMVER %q", got.Type, events(got), test.want)
+			}
+		})
+	}
+}
+
+func TestParseVersionRangeErrors(t *testing.T) {
+	for _, test := range []struct {
+		in, want string
+	}{
+		{"", "empty"},
+		{"   ", "empty"},
+		{">=", "missing version"},
+		{"<", "missing version"},
+		{">= 1.0.0,", "empty clause"},
+		{">= x", "invalid semantic version"},
+		{">= 1.0.0, >= 1.1.0", "more than one lower or upper bound"},
+		{"< 1.0.0, <= 2.0.0", "more than one lower or upper bound"},
+		{"= 1.0.0, = 1.0.0", "more than one lower or upper bound"},
+		{"= 1.0.0, < 2.0.0", "= must be the only clause"},
+		{">= 2.0.0, < 1.0.0", "no version satisfies it"},
+		{">= 1.0.0, < 1.0.0", "no version satisfies it"},
+		{"> 1.0.0, <= 1.0.0", "no version satisfies it"},
+	} {
+		t.Run(test.in, func(t *testing.T) {
+			_, err := ParseVersionRange(test.in)
+			if err == nil || !strings.Contains(err.Error(), test.want) {
+				t.Errorf("got error %v, want one containing %q", err, test.want)
+			}
+		})
+	}
+}
+
+func TestParseVersionRange(t *testing.T) {
+	for _, test := range []struct {
+		in, want string
+	}{
+		{"= 1.0.0", "= v1.0.0"},
+		{"1.0", "= v1.0.0"},
+		{">= 1.0.3, < 1.2.0", ">= v1.0.3, < v1.2.0"},
+		{">=1.0.3,<1.2.0", ">= v1.0.3, < v1.2.0"},
+		{"<= go1.19.1", "<= v1.19.1"},
+		{"< v2.0.0+incompatible", "< v2.0.0+incompatible"},
+		{">= 1.0.0, <= 1.0.0", ">= v1.0.0, <= v1.0.0"},
+	} {
+		r, err := ParseVersionRange(test.in)
+		if err != nil {
+			t.Errorf("%q: %v", test.in, err)
+			continue
+		}
+		if got := r.String(); got != test.want {
+			t.Errorf("%q: got %q, want %q", test.in, got, test.want)
+		}
+	}
+}
+
+func TestContains(t *testing.T) {
+	for _, test := range []struct {
+		rng string
+		in  []string
+		out []string
+	}{
+		{"= 1.0.0", []string{"1.0.0", "v1.0.0", "1.0"}, []string{"0.9.9", "1.0.1"}},
+		{"< 1.2.0", []string{"0.0.1", "1.1.9", "1.2.0-rc.1"}, []string{"1.2.0", "1.2.1"}},
+		{"<= 1.2.0", []string{"1.1.9", "1.2.0"}, []string{"1.2.1"}},
+		{"> 1.0.0", []string{"1.0.1", "2.0.0"}, []string{"1.0.0", "0.9.0", "1.0.0-rc.1"}},
+		{">= 1.0.3, < 1.2.0", []string{"1.0.3", "1.1.0", "1.1.9"}, []string{"1.0.2", "1.2.0", "2.0.0"}},
+		{"< 1.19.1", []string{"go1.19", "go1.18.5"}, []string{"go1.19.1", "go1.20"}},
+		{"< 2.0.0+incompatible", []string{"1.9.9"}, []string{"2.0.0+incompatible", "2.0.1+incompatible"}},
+		{"< 0.1.0", []string{"0.0.0-20220101000000-abcdef123456"}, []string{"0.1.0"}},
+		{"< 1.0.0", nil, []string{"", "not-a-version"}},
+	} {
+		r, err := ParseVersionRange(test.rng)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, want := range []bool{true, false} {
+			vs := test.in
+			if !want {
+				vs = test.out
+			}
+			for _, v := range vs {
+				if got := r.Contains(v); got != want {
+					t.Errorf("%s.Contains(%q) = %t, want %t", r, v, got, want)
+				}
+			}
+		}
+	}
+}
+
+func TestOverlaps(t *testing.T) {
+	for _, test := range []struct {
+		a, b string
+		want bool
+	}{
+		{"< 1.2.0", "< 1.0.0", true},
+		{">= 1.0.0, < 1.2.0", ">= 1.1.0, < 2.0.0", true},
+		{">= 1.0.0, < 1.2.0", ">= 1.2.0", false},
+		{">= 1.0.0, <= 1.2.0", ">= 1.2.0", true},
+		{"< 1.2.0", "> 1.2.0", false},
+		{"<= 1.2.0", "> 1.2.0", false},
+		{"= 1.2.0", ">= 1.0.0, < 2.0.0", true},
+		{"= 1.2.0", "= 1.2.1", false},
+		{"= 1.2.0", "<= 1.2.0", true},
+		{"= 1.2.0", "< 1.2.0", false},
+		{"> 1.0.0", "< 1.0.1", true},
+	} {
+		a, err := ParseVersionRange(test.a)
+		if err != nil {
+			t.Fatal(err)
+		}
+		b, err := ParseVersionRange(test.b)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := a.Overlaps(b); got != test.want {
+			t.Errorf("%q overlaps %q = %t, want %t", test.a, test.b, got, test.want)
+		}
+		if got := b.Overlaps(a); got != test.want {
+			t.Errorf("%q overlaps %q = %t, want %t", test.b, test.a, got, test.want)
+		}
+	}
+}
+
+func TestNormalizeVersion(t *testing.T) {
+	for _, test := range []struct {
+		in, want string
+	}{
+		{"1", "v1.0.0"},
+		{"1.2", "v1.2.0"},
+		{"v1.2.3", "v1.2.3"},
+		{" 1.2.3 ", "v1.2.3"},
+		{"go1.19", "v1.19.0"},
+		{"go1.19.1", "v1.19.1"},
+		{"1.2.3-rc.1", "v1.2.3-rc.1"},
+		{"2.0.0+incompatible", "v2.0.0+incompatible"},
+		{"0.0.0-20220101000000-abcdef123456", "v0.0.0-20220101000000-abcdef123456"},
+		{"", "error"},
+		{"v", "error"},
+		{"1.2.3.4", "error"},
+	} {
+		got, err := NormalizeVersion(test.in)
+		if err != nil {
+			got = "error"
+		}
+		if got != test.want {
+			t.Errorf("NormalizeVersion(%q) = %s, want %s", test.in, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,188 @@
+// Copyright 2022 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package githubtest provides an in-process fake of the parts of the GitHub
+// REST and GraphQL APIs used by this module, for use in tests.
+//
+// The fake serves the repository issues endpoints for listing, creating and
+// editing issues, listing their events, changing their labels and adding
+// comments to them, and the securityAdvisories and securityAdvisory GraphQL
+// queries. Point a client at it with client.WithRESTURL(s.RESTURL()) and
+// client.WithGraphQLURL(s.GraphQLURL()).
+package githubtest
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/google/go-github/v41/github"
+)
+
+// A Server is a fake GitHub API server.
+type Server struct {
+	srv   *httptest.Server
+	owner string
+	repo  string
+
+	mu sync.Mutex
+	// pageSize is the maximum number of advisories returned per GraphQL
+	// page. It does not affect issues, whose page size is set by the client.
+	pageSize int
+	issues   map[int]*github.Issue
+	// stateReasons holds the state_reason of issues, which github.Issue
+	// does not have a field for.
+	stateReasons map[int]string
+	events       map[int][]*github.IssueEvent
+	comments     map[int][]string
+	advisories   []*Advisory
+	// failures are returned, in order, instead of serving requests.
+	failures []failure
+	requests int
+}
+
+type failure struct {
+	status int
+	header http.Header
+}
+
+// NewServer starts and returns a Server for the repository owner/repo.
+// The caller should call Close when finished, to shut it down.
+func NewServer(owner, repo string) *Server {
+	s := &Server{
+		owner:        owner,
+		repo:         repo,
+		pageSize:     100,
+		issues:       map[int]*github.Issue{},
+		stateReasons: map[int]string{},
+		events:       map[int][]*github.IssueEvent{},
+		comments:     map[int][]string{},
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc(fmt.Sprintf("/repos/%s/%s/issues", owner, repo), s.handleIssues)
+	mux.HandleFunc(fmt.Sprintf("/repos/%s/%s/issues/", owner, repo), s.handleIssue)
+	mux.HandleFunc("/graphql", s.handleGraphQL)
+	s.srv = httptest.NewServer(s.wrap(mux))
+	return s
+}
+
+// Close shuts down the server.
+func (s *Server) Close() {
+	s.srv.Close()
+}
+
+// RESTURL returns the base URL of the fake REST API.
+func (s *Server) RESTURL() string {
+	return s.srv.URL + "/"
+}
+
+// GraphQLURL returns the URL of the fake GraphQL API.
+func (s *Server) GraphQLURL() string {
+	return s.srv.URL + "/graphql"
+}
+
+// HTTPClient returns an HTTP client configured to talk to the server.
+func (s *Server) HTTPClient() *http.Client {
+	return s.srv.Client()
+}
+
+// SetPageSize sets the maximum number of advisories returned per page of a
+// securityAdvisories query, so that tests can exercise pagination.
+func (s *Server) SetPageSize(n int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.pageSize = n
+}
+
+// Fail makes the next n requests fail with the given status and headers,
+// before the server resumes normal operation.
+func (s *Server) Fail(n, status int, header http.Header) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for k := 0; k < n; k++ {
+		s.failures = append(s.failures, failure{status: status, header: header})
+	}
+}
+
+// Requests returns the number of requests the server has received,
+// including failed ones.
+func (s *Server) Requests() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.requests
+}
+
+// rateLimit is the number of requests per hour reported in the
+// X-RateLimit-Limit header. The server does not enforce it.
+const rateLimit = 5000
+
+// wrap returns a handler that counts requests, reports rate limits and
+// injects the failures requested by Fail before calling h.
+func (s *Server) wrap(h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.mu.Lock()
+		s.requests++
+		var f *failure
+		if len(s.failures) > 0 {
+			f = &s.failures[0]
+			s.failures = s.failures[1:]
+		}
+		remaining := rateLimit - s.requests
+		s.mu.Unlock()
+		if remaining < 0 {
+			remaining = 0
+		}
+		resource := "core"
+		if r.URL.Path == "/graphql" {
+			resource = "graphql"
+		}
+		w.Header().Set("X-RateLimit-Limit", strconv.Itoa(rateLimit))
+		w.Header().Set("X-RateLimit-Remaining", strconv.Itoa(remaining))
+		w.Header().Set("X-RateLimit-Reset", strconv.FormatInt(time.Now().Add(time.Hour).Unix(), 10))
+		w.Header().Set("X-RateLimit-Resource", resource)
+		if f != nil {
+			// The failure's headers override the defaults above.
+			for k, vs := range f.header {
+				w.Header()[http.CanonicalHeaderKey(k)] = vs
+			}
+			http.Error(w, http.StatusText(f.status), f.status)
+			return
+		}
+		h.ServeHTTP(w, r)
+	})
+}
+
+// readJSON decodes the JSON in filename into v.
+func readJSON(filename string, v any) error {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		return fmt.Errorf("%s: %v", filename, err)
+	}
+	return nil
+}
+
+func writeJSON(w http.ResponseWriter, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+// intParam returns the integer value of the query parameter name, or def if
+// it is absent.
+func intParam(r *http.Request, name string, def int) (int, error) {
+	v := r.URL.Query().Get(name)
+	if v == "" {
+		return def, nil
+	}
+	return strconv.Atoi(v)
+}
@@ -0,0 +1,267 @@
+// Copyright 2022 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package githubtest
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// An Advisory is a GitHub security advisory served by the fake GraphQL API.
+//
+// In fixture files, advisories use the field names of the GraphQL schema,
+// except that vulnerabilities is a plain array rather than a connection.
+type Advisory struct {
+	ID              string           `json:"id"`
+	GHSAID          string           `json:"ghsaId"`
+	Identifiers     []Identifier     `json:"identifiers"`
+	Summary         string           `json:"summary"`
+	Description     string           `json:"description"`
+	Origin          string           `json:"origin"`
+	Permalink       string           `json:"permalink"`
+	References      []Reference      `json:"references"`
+	PublishedAt     time.Time        `json:"publishedAt"`
+	UpdatedAt       time.Time        `json:"updatedAt"`
+	Vulnerabilities []*Vulnerability `json:"vulnerabilities"`
+}
+
+// An Identifier identifies an advisory, for example by GHSA or CVE.
+type Identifier struct {
+	Type  string `json:"type"`
+	Value string `json:"value"`
+}
+
+// A Reference is a link to more information about an advisory.
+type Reference struct {
+	URL string `json:"url"`
+}
+
+// A Vulnerability is a package affected by an advisory.
+type Vulnerability struct {
+	Package struct {
+		Name      string `json:"name"`
+		Ecosystem string `json:"ecosystem"`
+	} `json:"package"`
+	// FirstPatchedVersion is empty if there is no fix.
+	FirstPatchedVersion    string    `json:"firstPatchedVersion"`
+	Severity               string    `json:"severity"`
+	UpdatedAt              time.Time `json:"updatedAt"`
+	VulnerableVersionRange string    `json:"vulnerableVersionRange"`
+}
+
+// AddAdvisories adds security advisories, replacing any existing advisories
+// with the same GHSA IDs.
+func (s *Server) AddAdvisories(advisories ...*Advisory) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, a := range advisories {
+		replaced := false
+		for k, old := range s.advisories {
+			if old.GHSAID == a.GHSAID {
+				s.advisories[k] = a
+				replaced = true
+				break
+			}
+		}
+		if !replaced {
+			s.advisories = append(s.advisories, a)
+		}
+	}
+}
+
+// LoadAdvisories adds the advisories in a JSON fixture file, which holds an
+// array of Advisory values.
+func (s *Server) LoadAdvisories(filename string) error {
+	var advisories []*Advisory
+	if err := readJSON(filename, &advisories); err != nil {
+		return err
+	}
+	s.AddAdvisories(advisories...)
+	return nil
+}
+
+type graphQLRequest struct {
+	Query     string                     `json:"query"`
+	Variables map[string]json.RawMessage `json:"variables"`
+}
+
+type graphQLError struct {
+	Message string `json:"message"`
+}
+
+// handleGraphQL serves POST /graphql.
+//
+// Only the securityAdvisories and securityAdvisory queries are supported.
+// The query text is not otherwise parsed, so responses always contain the
+// fields selected by the client package.
+func (s *Server) handleGraphQL(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return
+	}
+	var req graphQLRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	var (
+		data any
+		err  error
+	)
+	switch {
+	case strings.Contains(req.Query, "securityAdvisory(ghsaId:"):
+		data, err = s.securityAdvisory(req.Variables)
+	case strings.Contains(req.Query, "securityAdvisories("):
+		data, err = s.securityAdvisories(req.Variables)
+	default:
+		err = fmt.Errorf("unsupported query: %s", req.Query)
+	}
+	if err != nil {
+		writeJSON(w, map[string]any{"errors": []graphQLError{{Message: err.Error()}}})
+		return
+	}
+	writeJSON(w, map[string]any{"data": data})
+}
+
+func (s *Server) securityAdvisory(vars map[string]json.RawMessage) (any, error) {
+	var id string
+	if err := json.Unmarshal(vars["id"], &id); err != nil {
+		return nil, fmt.Errorf("ghsaId: %v", err)
+	}
+	goOnly := vars["go"] != nil
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, a := range s.advisories {
+		if a.GHSAID == id {
+			return map[string]any{"securityAdvisory": advisoryNode(a, goOnly)}, nil
+		}
+	}
+	return nil, fmt.Errorf("Could not resolve to a SecurityAdvisory with the GHSA ID of '%s'.", id)
+}
+
+func (s *Server) securityAdvisories(vars map[string]json.RawMessage) (any, error) {
+	var (
+		since  time.Time
+		cursor string
+		filter *Identifier
+	)
+	if v, ok := vars["since"]; ok {
+		if err := json.Unmarshal(v, &since); err != nil {
+			return nil, fmt.Errorf("updatedSince: %v", err)
+		}
+	}
+	if v, ok := vars["cursor"]; ok && string(v) != "null" {
+		if err := json.Unmarshal(v, &cursor); err != nil {
+			return nil, fmt.Errorf("after: %v", err)
+		}
+	}
+	if v, ok := vars["id"]; ok {
+		filter = &Identifier{}
+		if err := json.Unmarshal(v, filter); err != nil {
+			return nil, fmt.Errorf("identifier: %v", err)
+		}
+	}
+	goOnly := vars["go"] != nil
+	start := 0
+	if cursor != "" {
+		n, err := strconv.Atoi(cursor)
+		if err != nil {
+			return nil, fmt.Errorf("bad cursor %q", cursor)
+		}
+		start = n
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var matches []*Advisory
+	for _, a := range s.advisories {
+		if !since.IsZero() && a.UpdatedAt.Before(since) {
+			continue
+		}
+		if filter != nil && !hasIdentifier(a, *filter) {
+			continue
+		}
+		matches = append(matches, a)
+	}
+	if start > len(matches) {
+		start = len(matches)
+	}
+	end := start + s.pageSize
+	if end > len(matches) {
+		end = len(matches)
+	}
+	nodes := []any{}
+	for _, a := range matches[start:end] {
+		nodes = append(nodes, advisoryNode(a, goOnly))
+	}
+	return map[string]any{
+		"securityAdvisories": map[string]any{
+			"nodes": nodes,
+			"pageInfo": map[string]any{
+				"endCursor":   strconv.Itoa(end),
+				"hasNextPage": end < len(matches),
+			},
+		},
+	}, nil
+}
+
+func hasIdentifier(a *Advisory, id Identifier) bool {
+	for _, id2 := range a.Identifiers {
+		if id2 == id {
+			return true
+		}
+	}
+	return false
+}
+
+// advisoryNode returns the GraphQL representation of a. If goOnly is set,
+// only vulnerabilities in the Go ecosystem are included.
+func advisoryNode(a *Advisory, goOnly bool) map[string]any {
+	vulns := []any{}
+	for _, v := range a.Vulnerabilities {
+		if goOnly && v.Package.Ecosystem != "GO" {
+			continue
+		}
+		var patched any
+		if v.FirstPatchedVersion != "" {
+			patched = map[string]any{"identifier": v.FirstPatchedVersion}
+		}
+		vulns = append(vulns, map[string]any{
+			"package":                v.Package,
+			"firstPatchedVersion":    patched,
+			"severity":               v.Severity,
+			"updatedAt":              v.UpdatedAt,
+			"vulnerableVersionRange": v.VulnerableVersionRange,
+		})
+	}
+	identifiers := a.Identifiers
+	if identifiers == nil {
+		identifiers = []Identifier{}
+	}
+	references := a.References
+	if references == nil {
+		references = []Reference{}
+	}
+	return map[string]any{
+		"id":          a.ID,
+		"identifiers": identifiers,
+		"summary":     a.Summary,
+		"description": a.Description,
+		"origin":      a.Origin,
+		"permalink":   a.Permalink,
+		"references":  references,
+		"publishedAt": a.PublishedAt,
+		"updatedAt":   a.UpdatedAt,
+		"vulnerabilities": map[string]any{
+			"nodes":    vulns,
+			"pageInfo": map[string]any{"hasNextPage": false},
+		},
+	}
+}
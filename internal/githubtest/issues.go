@@ -0,0 +1,434 @@
+// Copyright 2022 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package githubtest
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/go-github/v41/github"
+)
+
+// AddIssues adds issues to the repository, replacing any existing issues
+// with the same numbers. Issues without a state are open, and issues without
+// an update time are treated as updated when they were created.
+func (s *Server) AddIssues(issues ...*github.Issue) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, iss := range issues {
+		if iss.State == nil {
+			iss.State = github.String("open")
+		}
+		if iss.UpdatedAt == nil {
+			iss.UpdatedAt = iss.CreatedAt
+		}
+		s.issues[iss.GetNumber()] = iss
+	}
+}
+
+// LoadIssues adds the issues in a JSON fixture file, which holds an array of
+// issues in the format returned by the GitHub REST API.
+func (s *Server) LoadIssues(filename string) error {
+	var issues []*github.Issue
+	if err := readJSON(filename, &issues); err != nil {
+		return err
+	}
+	s.AddIssues(issues...)
+	return nil
+}
+
+// SetIssueState sets the state of an issue to "open" or "closed", with a
+// state reason such as "completed" or "not_planned", and marks it as updated
+// at the given time. If the state changes, a "closed" or "reopened" event is
+// recorded.
+func (s *Server) SetIssueState(number int, state, reason string, updated time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.issues[number]; !ok {
+		return fmt.Errorf("no issue %d", number)
+	}
+	s.setState(number, state, reason, updated)
+	return nil
+}
+
+// setState implements SetIssueState. s.mu must be held.
+func (s *Server) setState(number int, state, reason string, updated time.Time) {
+	iss := s.issues[number]
+	if iss.GetState() != state {
+		typ := "reopened"
+		if state == "closed" {
+			typ = "closed"
+		}
+		s.addEvent(number, typ, "", updated)
+	}
+	iss.State = github.String(state)
+	s.stateReasons[number] = reason
+	iss.UpdatedAt = &updated
+	if state == "closed" {
+		iss.ClosedAt = &updated
+	} else {
+		iss.ClosedAt = nil
+	}
+}
+
+// AddIssueEvents appends events to the timeline of an issue, and marks the
+// issue as updated at the time of the latest event.
+func (s *Server) AddIssueEvents(number int, events ...*github.IssueEvent) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	iss, ok := s.issues[number]
+	if !ok {
+		return fmt.Errorf("no issue %d", number)
+	}
+	for _, e := range events {
+		s.events[number] = append(s.events[number], e)
+		if t := e.GetCreatedAt(); t.After(iss.GetUpdatedAt()) {
+			iss.UpdatedAt = &t
+		}
+	}
+	return nil
+}
+
+// A restIssue adds the fields that github.Issue lacks.
+type restIssue struct {
+	*github.Issue
+	StateReason string `json:"state_reason,omitempty"`
+}
+
+// handleIssues serves GET and POST /repos/OWNER/REPO/issues.
+//
+// GET supports the state, since, page and per_page parameters, and returns
+// issues newest first, as GitHub does by default.
+func (s *Server) handleIssues(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+	case http.MethodPost:
+		s.handleCreateIssue(w, r)
+		return
+	default:
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return
+	}
+	q := r.URL.Query()
+	state := q.Get("state")
+	if state == "" {
+		state = "open"
+	}
+	var since time.Time
+	if v := q.Get("since"); v != "" {
+		t, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+			return
+		}
+		since = t
+	}
+	page, err := intParam(r, "page", 1)
+	if err != nil || page < 1 {
+		http.Error(w, "bad page", http.StatusBadRequest)
+		return
+	}
+	perPage, err := intParam(r, "per_page", 30)
+	if err != nil || perPage < 1 {
+		http.Error(w, "bad per_page", http.StatusBadRequest)
+		return
+	}
+
+	s.mu.Lock()
+	var matches []*github.Issue
+	for _, iss := range s.issues {
+		if state != "all" && iss.GetState() != state {
+			continue
+		}
+		if !since.IsZero() && iss.GetUpdatedAt().Before(since) {
+			continue
+		}
+		matches = append(matches, iss)
+	}
+	s.mu.Unlock()
+	sort.Slice(matches, func(i, j int) bool {
+		return matches[i].GetNumber() > matches[j].GetNumber()
+	})
+
+	start, end := pageBounds(len(matches), page, perPage)
+	if end < len(matches) {
+		s.setNextLink(w, r, page)
+	}
+	out := []any{}
+	s.mu.Lock()
+	for _, iss := range matches[start:end] {
+		out = append(out, restIssue{Issue: iss, StateReason: s.stateReasons[iss.GetNumber()]})
+	}
+	s.mu.Unlock()
+	writeJSON(w, out)
+}
+
+// handleCreateIssue creates an issue with the next unused number, and
+// returns it with status 201, as GitHub does.
+func (s *Server) handleCreateIssue(w http.ResponseWriter, r *http.Request) {
+	var req github.IssueRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if req.GetTitle() == "" {
+		http.Error(w, "title is required", http.StatusUnprocessableEntity)
+		return
+	}
+	now := time.Now().UTC()
+	iss := &github.Issue{
+		Title:     req.Title,
+		Body:      req.Body,
+		State:     github.String("open"),
+		CreatedAt: &now,
+		UpdatedAt: &now,
+	}
+	for _, l := range req.GetLabels() {
+		iss.Labels = append(iss.Labels, &github.Label{Name: github.String(l)})
+	}
+	s.mu.Lock()
+	n := 1
+	for k := range s.issues {
+		if k >= n {
+			n = k + 1
+		}
+	}
+	iss.Number = github.Int(n)
+	s.issues[n] = iss
+	s.mu.Unlock()
+	w.WriteHeader(http.StatusCreated)
+	writeJSON(w, iss)
+}
+
+// handleIssue serves the endpoints under /repos/OWNER/REPO/issues/NUMBER/.
+func (s *Server) handleIssue(w http.ResponseWriter, r *http.Request) {
+	prefix := fmt.Sprintf("/repos/%s/%s/issues/", s.owner, s.repo)
+	parts := strings.SplitN(strings.TrimPrefix(r.URL.Path, prefix), "/", 3)
+	number, err := strconv.Atoi(parts[0])
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+	s.mu.Lock()
+	_, exists := s.issues[number]
+	s.mu.Unlock()
+	if !exists {
+		http.NotFound(w, r)
+		return
+	}
+	switch {
+	case len(parts) == 1 && r.Method == http.MethodPatch:
+		s.handleEditIssue(w, r, number)
+	case len(parts) == 2 && parts[1] == "events" && r.Method == http.MethodGet:
+		s.handleIssueEvents(w, r, number)
+	case len(parts) == 2 && parts[1] == "labels" && (r.Method == http.MethodPost || r.Method == http.MethodPut):
+		s.handleSetLabels(w, r, number)
+	case len(parts) == 2 && parts[1] == "comments" && r.Method == http.MethodPost:
+		s.handleAddComment(w, r, number)
+	case len(parts) == 3 && parts[1] == "labels" && r.Method == http.MethodDelete:
+		s.handleRemoveLabel(w, r, number, parts[2])
+	default:
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+	}
+}
+
+// handleEditIssue serves PATCH /repos/OWNER/REPO/issues/NUMBER. It supports
+// changing the title, body, state and state_reason.
+func (s *Server) handleEditIssue(w http.ResponseWriter, r *http.Request, number int) {
+	var req struct {
+		Title       *string `json:"title"`
+		Body        *string `json:"body"`
+		State       *string `json:"state"`
+		StateReason *string `json:"state_reason"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if req.State != nil && *req.State != "open" && *req.State != "closed" {
+		http.Error(w, "bad state", http.StatusUnprocessableEntity)
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	iss := s.issues[number]
+	now := time.Now().UTC()
+	if req.Title != nil {
+		iss.Title = req.Title
+	}
+	if req.Body != nil {
+		iss.Body = req.Body
+	}
+	if req.State != nil {
+		reason := ""
+		if req.StateReason != nil {
+			reason = *req.StateReason
+		} else if *req.State == "closed" {
+			reason = "completed"
+		}
+		s.setState(number, *req.State, reason, now)
+	}
+	iss.UpdatedAt = &now
+	writeJSON(w, restIssue{Issue: iss, StateReason: s.stateReasons[number]})
+}
+
+// handleIssueEvents serves GET /repos/OWNER/REPO/issues/NUMBER/events.
+//
+// It supports the page and per_page parameters.
+func (s *Server) handleIssueEvents(w http.ResponseWriter, r *http.Request, number int) {
+	page, err := intParam(r, "page", 1)
+	if err != nil || page < 1 {
+		http.Error(w, "bad page", http.StatusBadRequest)
+		return
+	}
+	perPage, err := intParam(r, "per_page", 30)
+	if err != nil || perPage < 1 {
+		http.Error(w, "bad per_page", http.StatusBadRequest)
+		return
+	}
+
+	s.mu.Lock()
+	events := append([]*github.IssueEvent{}, s.events[number]...)
+	s.mu.Unlock()
+	start, end := pageBounds(len(events), page, perPage)
+	if end < len(events) {
+		s.setNextLink(w, r, page)
+	}
+	writeJSON(w, events[start:end])
+}
+
+// handleSetLabels serves POST /repos/OWNER/REPO/issues/NUMBER/labels, which
+// adds labels, and PUT, which replaces them. It records "labeled" and
+// "unlabeled" events, and returns the issue's labels.
+func (s *Server) handleSetLabels(w http.ResponseWriter, r *http.Request, number int) {
+	var names []string
+	if err := json.NewDecoder(r.Body).Decode(&names); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	iss := s.issues[number]
+	want := map[string]bool{}
+	for _, n := range names {
+		want[n] = true
+	}
+	now := time.Now().UTC()
+	var kept []*github.Label
+	for _, l := range iss.Labels {
+		if r.Method == http.MethodPut && !want[l.GetName()] {
+			s.addEvent(number, "unlabeled", l.GetName(), now)
+			continue
+		}
+		delete(want, l.GetName())
+		kept = append(kept, l)
+	}
+	for _, n := range names {
+		if want[n] {
+			delete(want, n)
+			kept = append(kept, &github.Label{Name: github.String(n)})
+			s.addEvent(number, "labeled", n, now)
+		}
+	}
+	iss.Labels = kept
+	iss.UpdatedAt = &now
+	writeJSON(w, kept)
+}
+
+// handleRemoveLabel serves DELETE /repos/OWNER/REPO/issues/NUMBER/labels/NAME.
+// Like GitHub, it returns 404 if the issue does not have the label.
+func (s *Server) handleRemoveLabel(w http.ResponseWriter, r *http.Request, number int, name string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	iss := s.issues[number]
+	var kept []*github.Label
+	for _, l := range iss.Labels {
+		if l.GetName() != name {
+			kept = append(kept, l)
+		}
+	}
+	if len(kept) == len(iss.Labels) {
+		http.Error(w, `{"message":"Label does not exist"}`, http.StatusNotFound)
+		return
+	}
+	now := time.Now().UTC()
+	iss.Labels = kept
+	iss.UpdatedAt = &now
+	s.addEvent(number, "unlabeled", name, now)
+	if kept == nil {
+		kept = []*github.Label{}
+	}
+	writeJSON(w, kept)
+}
+
+// handleAddComment serves POST /repos/OWNER/REPO/issues/NUMBER/comments.
+// The comment bodies can be read with Comments.
+func (s *Server) handleAddComment(w http.ResponseWriter, r *http.Request, number int) {
+	var req struct {
+		Body string `json:"body"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	iss := s.issues[number]
+	now := time.Now().UTC()
+	s.comments[number] = append(s.comments[number], req.Body)
+	iss.Comments = github.Int(iss.GetComments() + 1)
+	iss.UpdatedAt = &now
+	w.WriteHeader(http.StatusCreated)
+	writeJSON(w, &github.IssueComment{
+		ID:        github.Int64(int64(len(s.comments[number]))),
+		Body:      github.String(req.Body),
+		CreatedAt: &now,
+		UpdatedAt: &now,
+	})
+}
+
+// Comments returns the bodies of the comments added to an issue, oldest
+// first.
+func (s *Server) Comments(number int) []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string(nil), s.comments[number]...)
+}
+
+// addEvent records an event for an issue. s.mu must be held.
+func (s *Server) addEvent(number int, typ, label string, t time.Time) {
+	e := &github.IssueEvent{Event: github.String(typ), CreatedAt: &t}
+	if label != "" {
+		e.Label = &github.Label{Name: github.String(label)}
+	}
+	s.events[number] = append(s.events[number], e)
+}
+
+// pageBounds returns the bounds of the given page of n items.
+func pageBounds(n, page, perPage int) (start, end int) {
+	start = (page - 1) * perPage
+	if start > n {
+		start = n
+	}
+	end = start + perPage
+	if end > n {
+		end = n
+	}
+	return start, end
+}
+
+// setNextLink sets a Link header pointing to the page after page.
+func (s *Server) setNextLink(w http.ResponseWriter, r *http.Request, page int) {
+	next := *r.URL
+	q := next.Query()
+	q.Set("page", fmt.Sprint(page+1))
+	next.RawQuery = q.Encode()
+	w.Header().Set("Link", fmt.Sprintf(`<%s%s>; rel="next"`, s.srv.URL, next.RequestURI()))
+}
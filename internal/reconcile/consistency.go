@@ -0,0 +1,293 @@
+// Copyright 2022 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package reconcile
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/julieqiu/derrors"
+	log "github.com/julieqiu/dlog"
+	"github.com/julieqiu/github/internal/client"
+	"github.com/julieqiu/github/internal/ghsa"
+	"golang.org/x/mod/semver"
+	"golang.org/x/sync/errgroup"
+	"golang.org/x/vuln/osv"
+)
+
+// A MismatchKind is a way in which a vulndb report disagrees with a GHSA.
+type MismatchKind string
+
+const (
+	// The GHSA lists a package that the report does not.
+	MissingFromReport MismatchKind = "missing-from-report"
+	// The report lists a package that the GHSA does not.
+	MissingFromGHSA MismatchKind = "missing-from-ghsa"
+	// The GHSA and the report fix the package in different versions.
+	FixedVersion MismatchKind = "fixed-version"
+	// The GHSA says a version is vulnerable that the report says is not.
+	CoversSafeVersion MismatchKind = "covers-safe-version"
+	// The GHSA's vulnerable version range cannot be parsed.
+	BadRange MismatchKind = "bad-range"
+)
+
+// A Mismatch is a disagreement between the vulndb report of an issue and
+// one of the GHSAs it is an alias of.
+type Mismatch struct {
+	Issue   int
+	Report  string
+	GHSA    string
+	Kind    MismatchKind
+	Package string
+	Message string
+}
+
+func (m *Mismatch) String() string {
+	return fmt.Sprintf("#%d %s/%s: %s: %s [%s]", m.Issue, m.Report, m.GHSA, m.Package, m.Message, m.Kind)
+}
+
+// A GHSAFetcher fetches an advisory by its GHSA ID.
+// It is implemented by *client.Client.
+type GHSAFetcher interface {
+	FetchGHSA(ctx context.Context, ghsaID string) (*client.SecurityAdvisory, error)
+}
+
+// CheckReports compares the report of each issue with the GHSAs that the
+// issue or its report mention, and returns the mismatches, ordered by issue
+// number. Advisories are looked up in known first, and fetched with f if they
+// are not there. If f is nil, advisories that are not known are skipped, as
+// are those that cannot be fetched, such as withdrawn ones; see Reconcile for
+// those. Issues without a report are skipped.
+func CheckReports(ctx context.Context, f GHSAFetcher, issues []*client.Issue, known []*client.SecurityAdvisory) (_ []*Mismatch, err error) {
+	defer derrors.Wrap(&err, "CheckReports")
+
+	advisories := map[string]*client.SecurityAdvisory{}
+	for _, sa := range known {
+		advisories[sa.PrettyID()] = sa
+	}
+	var missing []string
+	for _, i := range issues {
+		for _, id := range issueGHSAs(i) {
+			if advisories[id] == nil && !contains(missing, id) {
+				missing = append(missing, id)
+			}
+		}
+	}
+	if f != nil && len(missing) > 0 {
+		fetched := make([]*client.SecurityAdvisory, len(missing))
+		var g errgroup.Group
+		g.SetLimit(10)
+		for k, id := range missing {
+			k, id := k, id
+			g.Go(func() error {
+				sa, err := f.FetchGHSA(ctx, id)
+				if err != nil {
+					log.Warningf(ctx, "skipping %s: %v", id, err)
+					return nil
+				}
+				fetched[k] = sa
+				return nil
+			})
+		}
+		g.Wait()
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		for k, sa := range fetched {
+			if sa != nil {
+				advisories[missing[k]] = sa
+			}
+		}
+	}
+
+	var ms []*Mismatch
+	for _, i := range issues {
+		for _, id := range issueGHSAs(i) {
+			if sa := advisories[id]; sa != nil {
+				ms = append(ms, CompareReport(i.Number, i.OSV, id, sa)...)
+			}
+		}
+	}
+	sort.SliceStable(ms, func(i, j int) bool { return ms[i].Issue < ms[j].Issue })
+	return ms, nil
+}
+
+// issueGHSAs returns the GHSA IDs mentioned by the issue and by its report,
+// or nil if it has no report.
+func issueGHSAs(i *client.Issue) []string {
+	if i.OSV == nil {
+		return nil
+	}
+	var ids []string
+	for _, a := range append(append([]string(nil), i.Aliases...), i.OSV.Aliases...) {
+		if strings.HasPrefix(a, "GHSA-") && !contains(ids, a) {
+			ids = append(ids, a)
+		}
+	}
+	return ids
+}
+
+// CompareReport compares the affected packages and versions of a vulndb
+// report, created for the given issue, with those of the advisory whose ID
+// is ghsaID.
+//
+// A GHSA package matches a report package if they are the same, or if one
+// is a module that contains the other.
+func CompareReport(issue int, e *osv.Entry, ghsaID string, sa *client.SecurityAdvisory) []*Mismatch {
+	var ms []*Mismatch
+	add := func(kind MismatchKind, pkg, format string, args ...any) {
+		ms = append(ms, &Mismatch{
+			Issue:   issue,
+			Report:  e.ID,
+			GHSA:    ghsaID,
+			Kind:    kind,
+			Package: pkg,
+			Message: fmt.Sprintf(format, args...),
+		})
+	}
+
+	matched := make([]bool, len(e.Affected))
+	for _, v := range sa.Vulns {
+		var affs []osv.Affected
+		for k, a := range e.Affected {
+			if samePackage(v.Package, a.Package.Name) {
+				matched[k] = true
+				affs = append(affs, a)
+			}
+		}
+		if len(affs) == 0 {
+			add(MissingFromReport, v.Package, "GHSA lists %s but the report does not", v.Package)
+			continue
+		}
+		r, err := ghsa.ParseVersionRange(v.VulnerableVersionRange)
+		if err != nil {
+			add(BadRange, v.Package, "%v", err)
+			continue
+		}
+		var ranges osv.Affects
+		for _, a := range affs {
+			ranges = append(ranges, a.Ranges...)
+		}
+		compareVersions(r, v.EarliestFixedVersion, ranges, func(kind MismatchKind, format string, args ...any) {
+			add(kind, v.Package, format, args...)
+		})
+	}
+	for k, a := range e.Affected {
+		if !matched[k] {
+			add(MissingFromGHSA, a.Package.Name, "report lists %s but the GHSA does not", a.Package.Name)
+		}
+	}
+	return ms
+}
+
+// compareVersions compares the vulnerable versions of one package according
+// to a GHSA with those of a report.
+func compareVersions(r ghsa.VersionRange, earliestFixed string, ranges osv.Affects, add func(MismatchKind, string, ...any)) {
+	// The fixed versions of the report, as Go semantic versions.
+	var fixed []string
+	for _, ar := range ranges {
+		for _, ev := range ar.Events {
+			if ev.Fixed != "" {
+				if v, err := ghsa.NormalizeVersion(ev.Fixed); err == nil && !contains(fixed, v) {
+					fixed = append(fixed, v)
+				}
+			}
+		}
+	}
+	sort.Slice(fixed, func(i, j int) bool { return semver.Compare(fixed[i], fixed[j]) < 0 })
+
+	ghsaFixed := ""
+	if earliestFixed != "" {
+		if v, err := ghsa.NormalizeVersion(earliestFixed); err == nil {
+			ghsaFixed = v
+		}
+	}
+	switch {
+	case ghsaFixed == "" && len(fixed) > 0:
+		add(FixedVersion, "GHSA has no fixed version but the report is fixed in %s", strings.Join(fixed, ", "))
+	case ghsaFixed != "" && len(fixed) == 0:
+		add(FixedVersion, "GHSA is fixed in %s but the report has no fixed version", ghsaFixed)
+	case ghsaFixed != "" && !contains(fixed, ghsaFixed):
+		add(FixedVersion, "GHSA is fixed in %s but the report in %s", ghsaFixed, strings.Join(fixed, ", "))
+	}
+
+	// Check the versions at which either side changes from vulnerable to
+	// safe or back, which is where the two can first disagree.
+	candidates := []string{"v0.0.0"}
+	for _, c := range r {
+		candidates = append(candidates, c.Version)
+	}
+	for _, ar := range ranges {
+		for _, ev := range ar.Events {
+			for _, s := range []string{ev.Introduced, ev.Fixed} {
+				if v, err := ghsa.NormalizeVersion(s); err == nil {
+					candidates = append(candidates, v)
+				}
+			}
+		}
+	}
+	var safe []string
+	for _, v := range candidates {
+		if r.Contains(v) && !affects(ranges, v) && !contains(safe, v) {
+			safe = append(safe, v)
+		}
+	}
+	sort.Slice(safe, func(i, j int) bool { return semver.Compare(safe[i], safe[j]) < 0 })
+	if len(safe) > 0 {
+		add(CoversSafeVersion, "GHSA range %q covers versions that the report says are not vulnerable: %s", r, strings.Join(safe, ", "))
+	}
+}
+
+// affects reports whether the Go semantic version v is in any of the SEMVER
+// ranges. As in OSV, if there are no SEMVER ranges every version is affected.
+// The events of each range must be in increasing order of version.
+//
+// It differs from osv.Affects.AffectsSemver, which treats versions below the
+// first introduced version as affected if the range has a fixed version.
+func affects(ranges osv.Affects, v string) bool {
+	semverRange := false
+	for _, r := range ranges {
+		if r.Type != osv.TypeSemver {
+			continue
+		}
+		semverRange = true
+		affected := false
+		for _, ev := range r.Events {
+			if ev.Introduced != "" {
+				if ev.Introduced == "0" {
+					affected = true
+				} else if iv, err := ghsa.NormalizeVersion(ev.Introduced); err == nil && semver.Compare(v, iv) >= 0 {
+					affected = true
+				}
+			}
+			if ev.Fixed != "" {
+				if fv, err := ghsa.NormalizeVersion(ev.Fixed); err == nil && semver.Compare(v, fv) >= 0 {
+					affected = false
+				}
+			}
+		}
+		if affected {
+			return true
+		}
+	}
+	return !semverRange
+}
+
+// samePackage reports whether the paths are the same, or one is a prefix of
+// the other at a path separator.
+func samePackage(a, b string) bool {
+	return a == b || strings.HasPrefix(a, b+"/") || strings.HasPrefix(b, a+"/")
+}
+
+func contains(list []string, s string) bool {
+	for _, x := range list {
+		if x == s {
+			return true
+		}
+	}
+	return false
+}
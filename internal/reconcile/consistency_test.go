@@ -0,0 +1,146 @@
+// Copyright 2022 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package reconcile
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/julieqiu/github/internal/client"
+	"golang.org/x/vuln/osv"
+)
+
+// affected returns the OSV affected package pkg, with one SEMVER range
+// made of the events, given as "i1.0.0" for introduced and "f1.2.0" for
+// fixed.
+func affected(pkg string, events ...string) osv.Affected {
+	r := osv.AffectsRange{Type: osv.TypeSemver}
+	for _, e := range events {
+		switch e[0] {
+		case 'i':
+			r.Events = append(r.Events, osv.RangeEvent{Introduced: e[1:]})
+		case 'f':
+			r.Events = append(r.Events, osv.RangeEvent{Fixed: e[1:]})
+		}
+	}
+	return osv.Affected{Package: osv.Package{Name: pkg}, Ranges: osv.Affects{r}}
+}
+
+func TestCompareReport(t *testing.T) {
+	const id = "GHSA-8r3f-844c-mc37"
+	for _, test := range []struct {
+		name     string
+		vulns    []*client.Vuln
+		affected []osv.Affected
+		// want are the mismatches, as "kind package" lines.
+		want string
+	}{
+		{
+			name:     "agree",
+			vulns:    []*client.Vuln{{Package: "github.com/a/b", VulnerableVersionRange: ">= 1.0.0, < 1.2.0", EarliestFixedVersion: "1.2.0"}},
+			affected: []osv.Affected{affected("github.com/a/b", "i1.0.0", "f1.2.0")},
+		},
+		{
+			name:     "module contains package",
+			vulns:    []*client.Vuln{{Package: "github.com/a/b", VulnerableVersionRange: "< 1.2.0", EarliestFixedVersion: "1.2.0"}},
+			affected: []osv.Affected{affected("github.com/a/b/c", "i0", "f1.2.0")},
+		},
+		{
+			name:     "different fixed version",
+			vulns:    []*client.Vuln{{Package: "github.com/a/b", VulnerableVersionRange: "<= 1.2.0", EarliestFixedVersion: "1.2.2"}},
+			affected: []osv.Affected{affected("github.com/a/b", "i0", "f1.2.1")},
+			want:     "fixed-version github.com/a/b",
+		},
+		{
+			name:     "no fixed version in report",
+			vulns:    []*client.Vuln{{Package: "github.com/a/b", VulnerableVersionRange: "< 1.2.0", EarliestFixedVersion: "1.2.0"}},
+			affected: []osv.Affected{affected("github.com/a/b", "i0")},
+			want:     "fixed-version github.com/a/b",
+		},
+		{
+			name:     "later fixed version in GHSA",
+			vulns:    []*client.Vuln{{Package: "github.com/a/b", VulnerableVersionRange: "< 1.3.0", EarliestFixedVersion: "1.3.0"}},
+			affected: []osv.Affected{affected("github.com/a/b", "i0", "f1.2.0")},
+			want:     "fixed-version github.com/a/b\ncovers-safe-version github.com/a/b",
+		},
+		{
+			name:     "covers safe version",
+			vulns:    []*client.Vuln{{Package: "github.com/a/b", VulnerableVersionRange: "< 2.0.0", EarliestFixedVersion: "2.0.0"}},
+			affected: []osv.Affected{affected("github.com/a/b", "i1.0.0", "f2.0.0")},
+			want:     "covers-safe-version github.com/a/b",
+		},
+		{
+			name: "missing packages",
+			vulns: []*client.Vuln{
+				{Package: "github.com/a/b", VulnerableVersionRange: "< 1.2.0", EarliestFixedVersion: "1.2.0"},
+				{Package: "github.com/c/d", VulnerableVersionRange: "< 1.2.0", EarliestFixedVersion: "1.2.0"},
+			},
+			affected: []osv.Affected{affected("github.com/a/b", "i0", "f1.2.0"), affected("github.com/e/f", "i0", "f1.2.0")},
+			want:     "missing-from-report github.com/c/d\nmissing-from-ghsa github.com/e/f",
+		},
+		{
+			name:     "unparsable range",
+			vulns:    []*client.Vuln{{Package: "github.com/a/b", VulnerableVersionRange: ">= 2.0.0, < 1.0.0"}},
+			affected: []osv.Affected{affected("github.com/a/b", "i0", "f1.2.0")},
+			want:     "bad-range github.com/a/b",
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			e := &osv.Entry{ID: "GO-2022-0140", Affected: test.affected}
+			sa := &client.SecurityAdvisory{ID: id, Vulns: test.vulns}
+			var got []string
+			for _, m := range CompareReport(140, e, id, sa) {
+				if m.Issue != 140 || m.Report != e.ID || m.GHSA != id {
+					t.Errorf("mismatch %v is not for #140, %s and %s", m, e.ID, id)
+				}
+				got = append(got, string(m.Kind)+" "+m.Package)
+			}
+			if g := strings.Join(got, "\n"); g != test.want {
+				t.Errorf("got mismatches\n%s\nwant\n%s", g, test.want)
+			}
+		})
+	}
+}
+
+func TestCompareReportMessages(t *testing.T) {
+	e := &osv.Entry{ID: "GO-2022-0140", Affected: []osv.Affected{affected("github.com/a/b", "i1.0.0", "f1.2.0")}}
+	sa := &client.SecurityAdvisory{Vulns: []*client.Vuln{{Package: "github.com/a/b", VulnerableVersionRange: "< 1.3.0", EarliestFixedVersion: "1.3.0"}}}
+	var got []string
+	for _, m := range CompareReport(140, e, "GHSA-8r3f-844c-mc37", sa) {
+		got = append(got, m.Message)
+	}
+	want := []string{
+		"GHSA is fixed in v1.3.0 but the report in v1.2.0",
+		`GHSA range "< v1.3.0" covers versions that the report says are not vulnerable: v0.0.0, v1.2.0`,
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("got messages\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+}
+
+func TestAffects(t *testing.T) {
+	for _, test := range []struct {
+		ranges osv.Affects
+		in     []string
+		out    []string
+	}{
+		{affected("m", "i0", "f1.2.0").Ranges, []string{"v0.0.0", "v1.1.9"}, []string{"v1.2.0", "v2.0.0"}},
+		{affected("m", "i1.0.0", "f1.2.0").Ranges, []string{"v1.0.0", "v1.1.0"}, []string{"v0.9.0", "v1.2.0"}},
+		{affected("m", "i1.0.0", "f1.2.0", "i2.0.0", "f2.1.0").Ranges, []string{"v1.1.0", "v2.0.5"}, []string{"v1.5.0", "v2.1.0"}},
+		{affected("m", "i1.0.0").Ranges, []string{"v1.0.0", "v9.0.0"}, []string{"v0.1.0"}},
+		{nil, []string{"v0.0.0", "v1.0.0"}, nil},
+	} {
+		for _, v := range test.in {
+			if !affects(test.ranges, v) {
+				t.Errorf("%v: %s not affected, want affected", test.ranges, v)
+			}
+		}
+		for _, v := range test.out {
+			if affects(test.ranges, v) {
+				t.Errorf("%v: %s affected, want not affected", test.ranges, v)
+			}
+		}
+	}
+}
@@ -0,0 +1,171 @@
+// Copyright 2022 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package reconcile
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"text/template"
+
+	"github.com/julieqiu/derrors"
+	"github.com/julieqiu/github/internal/client"
+	"golang.org/x/mod/semver"
+)
+
+// An IssueCreator creates GitHub issues.
+// It is implemented by *client.Client.
+type IssueCreator interface {
+	CreateIssue(ctx context.Context, title, body string, labels []string) (int, error)
+}
+
+// A NewIssue is a tracking issue for an advisory.
+type NewIssue struct {
+	GHSA  string
+	Title string
+	Body  string
+	// Number is the number of the filed issue. It is zero in a dry run.
+	Number int
+}
+
+// IssueForGHSA returns the tracking issue for an advisory. Its title is in
+// the format "x/vulndb: potential Go vuln in <module>: <GHSA ID>" that
+// client.DefaultIssueFormats expects, where the module is that of the
+// advisory's first package; see modulePath. Its body has the sections that
+// client.ParseBody recognizes, and lists the advisory's CVEs.
+func IssueForGHSA(sa *client.SecurityAdvisory) (_ *NewIssue, err error) {
+	defer derrors.Wrap(&err, "IssueForGHSA(%s)", sa.PrettyID())
+	if len(sa.Vulns) == 0 {
+		return nil, errors.New("advisory has no Go packages")
+	}
+	var pkgs []string
+	for _, v := range sa.Vulns {
+		pkgs = append(pkgs, v.Package)
+	}
+	var b strings.Builder
+	if err := issueBodyTemplate.Execute(&b, sa); err != nil {
+		return nil, err
+	}
+	return &NewIssue{
+		GHSA:  sa.PrettyID(),
+		Title: fmt.Sprintf("x/vulndb: potential Go vuln in %s: %s", modulePath(pkgs), sa.PrettyID()),
+		Body:  b.String(),
+	}, nil
+}
+
+// modulePath guesses the path of the module containing the first of the
+// packages, from the paths alone.
+//
+// On the code hosts in hostModuleElems, it is the host's repository path,
+// followed by a major version suffix if there is one. Elsewhere, it is the
+// longest path prefix that the first package shares with the other packages
+// on the same host, and so is the first package itself if there are no
+// others.
+func modulePath(pkgs []string) string {
+	elems := strings.Split(pkgs[0], "/")
+	if n, ok := hostModuleElems[elems[0]]; ok {
+		if len(elems) <= n {
+			return pkgs[0]
+		}
+		if semver.IsValid(elems[n]) && semver.Major(elems[n]) == elems[n] && elems[n] != "v0" && elems[n] != "v1" {
+			n++
+		}
+		return strings.Join(elems[:n], "/")
+	}
+	n := len(elems)
+	for _, p := range pkgs[1:] {
+		es := strings.Split(p, "/")
+		if es[0] != elems[0] {
+			continue
+		}
+		k := 0
+		for k < n && k < len(es) && es[k] == elems[k] {
+			k++
+		}
+		// A module path is more than a host name.
+		if k > 1 {
+			n = k
+		}
+	}
+	return strings.Join(elems[:n], "/")
+}
+
+// hostModuleElems is the number of path elements in the repository paths of
+// well-known code hosts, such as 3 for "github.com/owner/repo".
+var hostModuleElems = map[string]int{
+	"github.com":        3,
+	"gitlab.com":        3,
+	"bitbucket.org":     3,
+	"golang.org":        3,
+	"gopkg.in":          2,
+	"google.golang.org": 2,
+	"k8s.io":            2,
+	"sigs.k8s.io":       2,
+}
+
+var issueBodyTemplate = template.Must(template.New("body").Parse(
+	`In GitHub Security Advisory [{{.PrettyID}}]({{.Permalink}}), there is a vulnerability in the following Go packages or modules:
+
+| Unit | Fixed | Vulnerable Ranges | Severity |
+| - | - | - | - |
+{{range .Vulns}}| [{{.Package}}](https://pkg.go.dev/{{.Package}}) | {{or .EarliestFixedVersion "none"}} | {{.VulnerableVersionRange}} | {{.Severity}} |
+{{end}}
+Summary: {{.Summary}}
+
+Description:
+{{.Description}}
+
+References:
+- ADVISORY: {{.Permalink}}
+{{- range .Identifiers}}{{if eq .Type "CVE"}}
+- WEB: https://nvd.nist.gov/vuln/detail/{{.Value}}{{end}}{{end}}
+`))
+
+// FileIssues files a tracking issue for each of the uncovered advisories,
+// and returns the issues it filed. If dryRun is true, it returns the issues
+// without filing them.
+//
+// An advisory is skipped if any of its IDs appears in the title of one of
+// the malformed issues, which Reconcile does not see, or if an issue has
+// already been filed for it by this call. Callers should reconcile against
+// a fresh list of issues just before calling FileIssues, so that it never
+// files an issue twice.
+func FileIssues(ctx context.Context, c IssueCreator, uncovered []*client.SecurityAdvisory, malformed []*client.MalformedIssue, dryRun bool) (_ []*NewIssue, err error) {
+	defer derrors.Wrap(&err, "FileIssues")
+
+	var filed []*NewIssue
+	seen := map[string]bool{}
+	for _, sa := range uncovered {
+		if seen[sa.PrettyID()] || mentionedIn(sa, malformed) {
+			continue
+		}
+		seen[sa.PrettyID()] = true
+		ni, err := IssueForGHSA(sa)
+		if err != nil {
+			return filed, err
+		}
+		if !dryRun {
+			if ni.Number, err = c.CreateIssue(ctx, ni.Title, ni.Body, nil); err != nil {
+				return filed, err
+			}
+		}
+		filed = append(filed, ni)
+	}
+	return filed, nil
+}
+
+// mentionedIn reports whether any ID of sa is in the title of one of the
+// issues.
+func mentionedIn(sa *client.SecurityAdvisory, issues []*client.MalformedIssue) bool {
+	for _, m := range issues {
+		for _, id := range advisoryIDs(sa) {
+			if strings.Contains(m.Title, id) {
+				return true
+			}
+		}
+	}
+	return false
+}
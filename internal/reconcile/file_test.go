@@ -0,0 +1,131 @@
+// Copyright 2022 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package reconcile
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/julieqiu/github/internal/client"
+)
+
+func TestIssueForGHSA(t *testing.T) {
+	sa := &client.SecurityAdvisory{
+		ID:          "GHSA-8r3f-844c-mc37",
+		Identifiers: []client.Identifier{{Type: "GHSA", Value: "GHSA-8r3f-844c-mc37"}, {Type: "CVE", Value: "CVE-2022-0001"}},
+		Summary:     "Two packages",
+		Permalink:   "https://github.com/advisories/GHSA-8r3f-844c-mc37",
+		Vulns: []*client.Vuln{
+			{Package: "github.com/example/two/v2/internal/a", VulnerableVersionRange: "< 2.1.0", EarliestFixedVersion: "2.1.0"},
+			{Package: "github.com/example/two/v2/b", VulnerableVersionRange: "< 2.1.0", EarliestFixedVersion: "2.1.0"},
+		},
+	}
+	ni, err := IssueForGHSA(sa)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "x/vulndb: potential Go vuln in github.com/example/two/v2: GHSA-8r3f-844c-mc37"; ni.Title != want {
+		t.Errorf("title = %q, want %q", ni.Title, want)
+	}
+	for _, want := range []string{"github.com/example/two/v2/internal/a", "github.com/example/two/v2/b", "CVE-2022-0001"} {
+		if !strings.Contains(ni.Body, want) {
+			t.Errorf("body does not mention %s:\n%s", want, ni.Body)
+		}
+	}
+
+	// The issue parses as one for the module and the advisory.
+	p, err := client.NewIssueParser()
+	if err != nil {
+		t.Fatal(err)
+	}
+	pi, err := p.Parse(ni.Title, ni.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pi.ModulePath != "github.com/example/two/v2" || len(pi.IDs) != 1 || pi.IDs[0] != sa.ID {
+		t.Errorf("parsed as module %q, IDs %v", pi.ModulePath, pi.IDs)
+	}
+}
+
+func TestModulePath(t *testing.T) {
+	for _, test := range []struct {
+		pkgs []string
+		want string
+	}{
+		{[]string{"github.com/a/b"}, "github.com/a/b"},
+		{[]string{"github.com/a/b/c/d"}, "github.com/a/b"},
+		{[]string{"github.com/a/b/v3/c"}, "github.com/a/b/v3"},
+		{[]string{"github.com/a/b/v1/c"}, "github.com/a/b"},
+		{[]string{"github.com/a"}, "github.com/a"},
+		{[]string{"golang.org/x/net/http2"}, "golang.org/x/net"},
+		{[]string{"gopkg.in/yaml.v2"}, "gopkg.in/yaml.v2"},
+		{[]string{"google.golang.org/grpc/credentials"}, "google.golang.org/grpc"},
+		{[]string{"example.com/m/a"}, "example.com/m/a"},
+		{[]string{"example.com/m/a", "example.com/m/b/c"}, "example.com/m"},
+		{[]string{"example.com/m/a", "other.com/m/a"}, "example.com/m/a"},
+		{[]string{"example.com/m/a", "example.com/n"}, "example.com/m/a"},
+		{[]string{"net/http"}, "net/http"},
+	} {
+		if got := modulePath(test.pkgs); got != test.want {
+			t.Errorf("modulePath(%q) = %q, want %q", test.pkgs, got, test.want)
+		}
+	}
+}
+
+// fakeCreator is an IssueCreator that numbers issues from 200.
+type fakeCreator struct {
+	titles []string
+}
+
+func (f *fakeCreator) CreateIssue(_ context.Context, title, _ string, _ []string) (int, error) {
+	f.titles = append(f.titles, title)
+	return 199 + len(f.titles), nil
+}
+
+func TestFileIssues(t *testing.T) {
+	ghsa := func(id, pkg string) *client.SecurityAdvisory {
+		return &client.SecurityAdvisory{
+			ID:          id,
+			Identifiers: []client.Identifier{{Type: "GHSA", Value: id}},
+			Vulns:       []*client.Vuln{{Package: pkg}},
+		}
+	}
+	uncovered := []*client.SecurityAdvisory{
+		ghsa("GHSA-8r3f-844c-mc37", "github.com/example/one"),
+		ghsa("GHSA-8r3f-844c-mc37", "github.com/example/one"),
+		ghsa("GHSA-vp56-6g26-6827", "github.com/example/two"),
+		ghsa("GHSA-q3j5-32m5-58c2", "github.com/example/three"),
+	}
+	malformed := []*client.MalformedIssue{{Number: 150, Title: "GHSA-vp56-6g26-6827 in example two"}}
+	ctx := context.Background()
+
+	var f fakeCreator
+	filed, err := FileIssues(ctx, &f, uncovered, malformed, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(filed) != 2 || len(f.titles) != 0 {
+		t.Fatalf("dry run: got %d issues and %d created, want 2 and 0", len(filed), len(f.titles))
+	}
+
+	filed, err = FileIssues(ctx, &f, uncovered, malformed, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, ni := range filed {
+		got = append(got, ni.GHSA)
+		if ni.Number == 0 {
+			t.Errorf("%s: no issue number", ni.GHSA)
+		}
+	}
+	if want := "GHSA-8r3f-844c-mc37 GHSA-q3j5-32m5-58c2"; strings.Join(got, " ") != want {
+		t.Errorf("filed %v, want %s", got, want)
+	}
+	if len(f.titles) != 2 {
+		t.Errorf("created %d issues, want 2", len(f.titles))
+	}
+}
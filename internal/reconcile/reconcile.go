@@ -0,0 +1,125 @@
+// Copyright 2022 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package reconcile matches the GitHub security advisories that affect Go
+// with the vulndb issues that track them.
+package reconcile
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/julieqiu/github/internal/client"
+	"golang.org/x/vuln/osv"
+)
+
+// A Result is the outcome of reconciling advisories with issues.
+type Result struct {
+	// Covered maps the GHSA ID of each advisory that has an issue to the
+	// numbers of the issues that track it, in increasing order.
+	Covered map[string][]int
+	// Uncovered are the advisories without an issue, newest first.
+	Uncovered []*client.SecurityAdvisory
+	// Orphaned are the issues that mention a GHSA that is not among the
+	// advisories, because it was withdrawn or no longer affects Go.
+	Orphaned []*Orphan
+}
+
+// An Orphan is an issue that mentions GHSAs that no longer exist.
+type Orphan struct {
+	Issue *client.Issue
+	GHSAs []string
+}
+
+// HasGaps reports whether any advisory lacks an issue or any issue mentions
+// a missing advisory.
+func (r *Result) HasGaps() bool {
+	return len(r.Uncovered) > 0 || len(r.Orphaned) > 0
+}
+
+// Reconcile matches advisories with issues. An advisory is covered by an
+// issue if the issue mentions its GHSA ID or any of its CVEs, or if the
+// vulndb report for the issue lists one of them as an alias.
+func Reconcile(issues []*client.Issue, ghsas []*client.SecurityAdvisory, reports []*osv.Entry) *Result {
+	// ids maps each GHSA, CVE and report alias to the issues that mention it.
+	ids := map[string][]int{}
+	add := func(id string, n int) {
+		for _, m := range ids[id] {
+			if m == n {
+				return
+			}
+		}
+		ids[id] = append(ids[id], n)
+	}
+	for _, i := range issues {
+		for _, a := range i.Aliases {
+			add(a, i.Number)
+		}
+		if i.OSV != nil {
+			for _, a := range i.OSV.Aliases {
+				add(a, i.Number)
+			}
+		}
+	}
+	for _, e := range reports {
+		n, err := client.ReportIssueNumber(e.ID)
+		if err != nil {
+			continue
+		}
+		for _, a := range e.Aliases {
+			add(a, n)
+		}
+	}
+
+	r := &Result{Covered: map[string][]int{}}
+	known := map[string]bool{}
+	for _, sa := range ghsas {
+		id := sa.PrettyID()
+		known[id] = true
+		var nums []int
+		seen := map[int]bool{}
+		for _, alias := range advisoryIDs(sa) {
+			for _, n := range ids[alias] {
+				if !seen[n] {
+					seen[n] = true
+					nums = append(nums, n)
+				}
+			}
+		}
+		if len(nums) == 0 {
+			r.Uncovered = append(r.Uncovered, sa)
+			continue
+		}
+		sort.Ints(nums)
+		r.Covered[id] = nums
+	}
+	sort.Slice(r.Uncovered, func(i, j int) bool {
+		return r.Uncovered[i].PublishedAt.After(r.Uncovered[j].PublishedAt)
+	})
+
+	for _, i := range issues {
+		var missing []string
+		for _, a := range i.Aliases {
+			if strings.HasPrefix(a, "GHSA-") && !known[a] {
+				missing = append(missing, a)
+			}
+		}
+		if len(missing) > 0 {
+			r.Orphaned = append(r.Orphaned, &Orphan{Issue: i, GHSAs: missing})
+		}
+	}
+	sort.Slice(r.Orphaned, func(i, j int) bool {
+		return r.Orphaned[i].Issue.Number > r.Orphaned[j].Issue.Number
+	})
+	return r
+}
+
+// advisoryIDs returns the GHSA ID and the other identifiers of sa.
+func advisoryIDs(sa *client.SecurityAdvisory) []string {
+	ids := []string{sa.PrettyID()}
+	for _, id := range sa.Identifiers {
+		ids = append(ids, id.Value)
+	}
+	return ids
+}
@@ -0,0 +1,94 @@
+// Copyright 2022 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package reconcile
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/julieqiu/github/internal/client"
+	"golang.org/x/vuln/osv"
+)
+
+func TestReconcile(t *testing.T) {
+	day := func(d int) time.Time { return time.Date(2022, 3, d, 0, 0, 0, 0, time.UTC) }
+	advisory := func(id string, published time.Time, cves ...string) *client.SecurityAdvisory {
+		sa := &client.SecurityAdvisory{
+			ID:          id,
+			Identifiers: []client.Identifier{{Type: "GHSA", Value: id}},
+			PublishedAt: published,
+		}
+		for _, c := range cves {
+			sa.Identifiers = append(sa.Identifiers, client.Identifier{Type: "CVE", Value: c})
+		}
+		return sa
+	}
+	ghsas := []*client.SecurityAdvisory{
+		// Covered by its GHSA ID.
+		advisory("GHSA-8r3f-844c-mc37", day(1)),
+		// Covered by its CVE, twice.
+		advisory("GHSA-vp56-6g26-6827", day(2), "CVE-2022-0001"),
+		// Covered by the aliases of an attached report.
+		advisory("GHSA-q3j5-32m5-58c2", day(3)),
+		// Covered by the aliases of a report from the database.
+		advisory("GHSA-2222-3333-4444", day(4), "CVE-2022-0004"),
+		// Uncovered.
+		advisory("GHSA-5555-6666-7777", day(5)),
+		advisory("GHSA-cccc-ffff-gggg", day(7), "CVE-2022-0007"),
+	}
+	issues := []*client.Issue{
+		{Number: 140, Aliases: []string{"GHSA-8r3f-844c-mc37"}},
+		{Number: 141, Aliases: []string{"CVE-2022-0001"}},
+		{Number: 142, Aliases: []string{"CVE-2022-0001", "GHSA-hhhh-jjjj-mmmm"}},
+		{Number: 143, Aliases: []string{"CVE-2022-0003"}, OSV: &osv.Entry{ID: "GO-2022-0143", Aliases: []string{"GHSA-q3j5-32m5-58c2"}}},
+		{Number: 144, Aliases: []string{"GHSA-pppp-qqqq-rrrr", "GHSA-xxxx-wwww-vvvv", "GHSA-8r3f-844c-mc37"}},
+	}
+	reports := []*osv.Entry{
+		{ID: "GO-2022-0145", Aliases: []string{"CVE-2022-0004"}},
+		{ID: "not-a-vulndb-id", Aliases: []string{"GHSA-5555-6666-7777"}},
+	}
+	r := Reconcile(issues, ghsas, reports)
+
+	var covered []string
+	for id, nums := range r.Covered {
+		covered = append(covered, fmt.Sprintf("%s %v", id, nums))
+	}
+	sort.Strings(covered)
+	want := []string{
+		"GHSA-2222-3333-4444 [145]",
+		"GHSA-8r3f-844c-mc37 [140 144]",
+		"GHSA-q3j5-32m5-58c2 [143]",
+		"GHSA-vp56-6g26-6827 [141 142]",
+	}
+	if strings.Join(covered, "\n") != strings.Join(want, "\n") {
+		t.Errorf("covered:\n%s\nwant\n%s", strings.Join(covered, "\n"), strings.Join(want, "\n"))
+	}
+
+	var uncovered []string
+	for _, sa := range r.Uncovered {
+		uncovered = append(uncovered, sa.PrettyID())
+	}
+	if got, want := strings.Join(uncovered, " "), "GHSA-cccc-ffff-gggg GHSA-5555-6666-7777"; got != want {
+		t.Errorf("uncovered = %s, want %s, newest first", got, want)
+	}
+
+	var orphaned []string
+	for _, o := range r.Orphaned {
+		orphaned = append(orphaned, fmt.Sprintf("#%d %v", o.Issue.Number, o.GHSAs))
+	}
+	if got, want := strings.Join(orphaned, "; "), "#144 [GHSA-pppp-qqqq-rrrr GHSA-xxxx-wwww-vvvv]; #142 [GHSA-hhhh-jjjj-mmmm]"; got != want {
+		t.Errorf("orphaned = %s, want %s", got, want)
+	}
+	if !r.HasGaps() {
+		t.Error("HasGaps = false, want true")
+	}
+
+	if r := Reconcile(issues[:1], ghsas[:1], nil); r.HasGaps() {
+		t.Errorf("HasGaps = true with every advisory covered: %+v", r)
+	}
+}
@@ -0,0 +1,211 @@
+// Copyright 2022 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package report drafts vulndb reports from GitHub security advisories.
+package report
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/julieqiu/github/internal/client"
+	"github.com/julieqiu/github/internal/ghsa"
+)
+
+// TODO is the placeholder for fields of a draft that a person must fill in.
+const TODO = "TODO"
+
+// A Report is a draft vulndb report, in the layout of the YAML files in
+// golang.org/x/vulndb/data/reports.
+type Report struct {
+	Modules     []*Module
+	Description string
+	CVEs        []string
+	GHSAs       []string
+	References  []*Reference
+	// Notes are things the draft could not work out, for the person
+	// finishing it. They are written as comments at the top of the YAML.
+	Notes []string
+}
+
+// A Module is a vulnerable module and its vulnerable packages.
+type Module struct {
+	Module string
+	// Versions are the vulnerable version ranges, with versions in OSV
+	// form, without a "v" prefix.
+	Versions []*VersionRange
+	// Packages are the vulnerable packages in the module. The GHSA only
+	// names the module or some of its packages, so these are a guess.
+	Packages []*Package
+}
+
+// A VersionRange is a range of vulnerable versions. An empty Introduced
+// means all versions before Fixed, and an empty Fixed means there is no fix.
+type VersionRange struct {
+	Introduced string
+	Fixed      string
+}
+
+// A Package is a vulnerable package and its vulnerable symbols.
+type Package struct {
+	Package string
+	Symbols []string
+}
+
+// A Reference is a link to more information, such as the fix. Type is one
+// of the vulndb reference types: "fix", "report", "advisory" or "web".
+type Reference struct {
+	Type string
+	URL  string
+}
+
+// FromGHSA returns a draft report for the Go packages of the advisory.
+// If modulePath is not empty, it is the module of the vulnerability, usually
+// the module path of the vulndb issue, and any package of the advisory
+// inside it belongs to it. Otherwise each package of the advisory is assumed
+// to be a module.
+//
+// The draft has placeholders for the vulnerable packages and symbols, and
+// Notes for anything else that must be checked.
+func FromGHSA(sa *client.SecurityAdvisory, modulePath string) *Report {
+	r := &Report{
+		Description: description(sa),
+	}
+	for _, id := range sa.Identifiers {
+		switch id.Type {
+		case "CVE":
+			r.CVEs = appendNew(r.CVEs, id.Value)
+		case "GHSA":
+			r.GHSAs = appendNew(r.GHSAs, id.Value)
+		}
+	}
+	if len(r.GHSAs) == 0 {
+		r.GHSAs = []string{sa.PrettyID()}
+	}
+	for _, u := range sa.References {
+		if u == sa.Permalink {
+			continue
+		}
+		r.References = append(r.References, &Reference{Type: referenceType(u), URL: u})
+	}
+
+	for _, v := range sa.Vulns {
+		mod := v.Package
+		if modulePath != "" && (v.Package == modulePath || strings.HasPrefix(v.Package, modulePath+"/")) {
+			mod = modulePath
+		}
+		m := r.module(mod)
+		vr, err := versions(v)
+		if err != nil {
+			r.Notes = append(r.Notes, fmt.Sprintf("%s: %v; fill in the versions by hand", v.Package, err))
+		} else {
+			m.addVersions(vr)
+		}
+		m.Packages = append(m.Packages, &Package{Package: v.Package, Symbols: []string{TODO}})
+	}
+	if len(r.Modules) == 0 {
+		r.Notes = append(r.Notes, "the advisory has no Go packages")
+	}
+	r.Notes = append(r.Notes, "check the vulnerable packages and fill in their symbols")
+	return r
+}
+
+// module returns the module of r with the given path, adding it if needed.
+func (r *Report) module(path string) *Module {
+	for _, m := range r.Modules {
+		if m.Module == path {
+			return m
+		}
+	}
+	m := &Module{Module: path}
+	r.Modules = append(r.Modules, m)
+	return m
+}
+
+// addVersions adds the ranges that m does not already have.
+func (m *Module) addVersions(vrs []*VersionRange) {
+	for _, vr := range vrs {
+		dup := false
+		for _, old := range m.Versions {
+			if *old == *vr {
+				dup = true
+				break
+			}
+		}
+		if !dup {
+			m.Versions = append(m.Versions, vr)
+		}
+	}
+}
+
+// versions converts the vulnerable version range of v; see
+// ghsa.VersionRange.OSVRange.
+func versions(v *client.Vuln) ([]*VersionRange, error) {
+	r, err := ghsa.ParseVersionRange(v.VulnerableVersionRange)
+	if err != nil {
+		return nil, err
+	}
+	ar, err := r.OSVRange(v.EarliestFixedVersion)
+	if err != nil {
+		return nil, err
+	}
+	var (
+		out []*VersionRange
+		cur *VersionRange
+	)
+	for _, ev := range ar.Events {
+		switch {
+		case ev.Introduced != "":
+			cur = &VersionRange{}
+			if ev.Introduced != "0" {
+				cur.Introduced = ev.Introduced
+			}
+			out = append(out, cur)
+		case ev.Fixed != "" && cur != nil:
+			cur.Fixed = ev.Fixed
+			cur = nil
+		}
+	}
+	return out, nil
+}
+
+// description returns the description of the advisory, or its summary if it
+// has none, with Windows line endings and surrounding space removed.
+func description(sa *client.SecurityAdvisory) string {
+	d := sa.Description
+	if strings.TrimSpace(d) == "" {
+		d = sa.Summary
+	}
+	return strings.TrimSpace(strings.ReplaceAll(d, "\r\n", "\n"))
+}
+
+// referenceType guesses the vulndb reference type of a link.
+func referenceType(u string) string {
+	pu, err := url.Parse(u)
+	if err != nil {
+		return "web"
+	}
+	path := pu.Path
+	switch {
+	case strings.Contains(path, "/commit/") || strings.Contains(path, "/pull/") ||
+		pu.Host == "go-review.googlesource.com" || pu.Host == "go.dev" && strings.HasPrefix(path, "/cl/"):
+		return "fix"
+	case strings.Contains(path, "/issues/") || pu.Host == "go.dev" && strings.HasPrefix(path, "/issue/"):
+		return "report"
+	case pu.Host == "nvd.nist.gov" || pu.Host == "github.com" && strings.HasPrefix(path, "/advisories/") ||
+		strings.Contains(path, "/security/advisories/"):
+		return "advisory"
+	}
+	return "web"
+}
+
+func appendNew(list []string, s string) []string {
+	for _, x := range list {
+		if x == s {
+			return list
+		}
+	}
+	return append(list, s)
+}
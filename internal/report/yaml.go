@@ -0,0 +1,154 @@
+// Copyright 2022 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package report
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// WriteYAML writes the report as YAML, in the layout that vulndb uses:
+//
+//	modules:
+//	  - module: github.com/example/module
+//	    versions:
+//	      - introduced: 1.0.0
+//	        fixed: 1.2.1
+//	    packages:
+//	      - package: github.com/example/module/pkg
+//	        symbols:
+//	          - TODO
+//	description: |
+//	    The description.
+//	cves:
+//	  - CVE-2022-1234
+//	ghsas:
+//	  - GHSA-xxxx-xxxx-xxxx
+//	references:
+//	  - fix: https://github.com/example/module/commit/abc
+//
+// The notes come first, as comments.
+func (r *Report) WriteYAML(w io.Writer) error {
+	var b bytes.Buffer
+	for _, n := range r.Notes {
+		fmt.Fprintf(&b, "# %s: %s\n", TODO, n)
+	}
+	if len(r.Modules) > 0 {
+		b.WriteString("modules:\n")
+	}
+	for _, m := range r.Modules {
+		fmt.Fprintf(&b, "  - module: %s\n", scalar(m.Module))
+		if len(m.Versions) > 0 {
+			b.WriteString("    versions:\n")
+		}
+		for _, v := range m.Versions {
+			prefix := "      - "
+			if v.Introduced != "" {
+				fmt.Fprintf(&b, "%sintroduced: %s\n", prefix, scalar(v.Introduced))
+				prefix = "        "
+			}
+			if v.Fixed != "" {
+				fmt.Fprintf(&b, "%sfixed: %s\n", prefix, scalar(v.Fixed))
+			} else if v.Introduced == "" {
+				// All versions are vulnerable.
+				fmt.Fprintf(&b, "%sintroduced: %s\n", prefix, scalar("0"))
+			}
+		}
+		if len(m.Packages) > 0 {
+			b.WriteString("    packages:\n")
+		}
+		for _, p := range m.Packages {
+			fmt.Fprintf(&b, "      - package: %s\n", scalar(p.Package))
+			writeList(&b, "        ", "symbols", p.Symbols)
+		}
+	}
+	if r.Description != "" {
+		b.WriteString("description: ")
+		writeBlock(&b, "    ", r.Description)
+	}
+	writeList(&b, "", "cves", r.CVEs)
+	writeList(&b, "", "ghsas", r.GHSAs)
+	if len(r.References) > 0 {
+		b.WriteString("references:\n")
+	}
+	for _, ref := range r.References {
+		fmt.Fprintf(&b, "  - %s: %s\n", ref.Type, scalar(ref.URL))
+	}
+	_, err := w.Write(b.Bytes())
+	return err
+}
+
+// writeList writes a key and a list of scalars at the given indentation, or
+// nothing if the list is empty.
+func writeList(b *bytes.Buffer, indent, key string, list []string) {
+	if len(list) == 0 {
+		return
+	}
+	fmt.Fprintf(b, "%s%s:\n", indent, key)
+	for _, s := range list {
+		fmt.Fprintf(b, "%s  - %s\n", indent, scalar(s))
+	}
+}
+
+// writeBlock writes s as a literal block scalar, each line indented, after
+// a key. If s cannot be written that way, it is written quoted.
+func writeBlock(b *bytes.Buffer, indent, s string) {
+	if strings.HasPrefix(s, " ") || strings.ContainsAny(s, "\t\r") || !isPrintable(s) {
+		b.WriteString(strconv.Quote(s) + "\n")
+		return
+	}
+	b.WriteString("|\n")
+	for _, line := range strings.Split(s, "\n") {
+		if line = strings.TrimRight(line, " "); line == "" {
+			b.WriteString("\n")
+			continue
+		}
+		b.WriteString(indent + line + "\n")
+	}
+}
+
+// scalar returns s as a YAML scalar, quoting it if it would otherwise be
+// read as something other than the string s.
+func scalar(s string) string {
+	if needsQuotes(s) {
+		return strconv.Quote(s)
+	}
+	return s
+}
+
+func needsQuotes(s string) bool {
+	if s == "" || s != strings.TrimSpace(s) || strings.Contains(s, "\n") || !isPrintable(s) {
+		return true
+	}
+	// Reserved indicators at the start of a plain scalar.
+	if strings.ContainsAny(s[:1], "-?:,[]{}#&*!|>'\"%@`") {
+		return true
+	}
+	if strings.Contains(s, ": ") || strings.Contains(s, " #") || strings.HasSuffix(s, ":") {
+		return true
+	}
+	// Words and numbers that YAML reads as booleans, nulls or numbers.
+	switch strings.ToLower(s) {
+	case "true", "false", "yes", "no", "on", "off", "y", "n", "null", "~":
+		return true
+	}
+	if _, err := strconv.ParseFloat(s, 64); err == nil {
+		return true
+	}
+	return false
+}
+
+// isPrintable reports whether s has only printable characters and newlines.
+func isPrintable(s string) bool {
+	for _, c := range s {
+		if c != '\n' && !strconv.IsPrint(c) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,218 @@
+// Copyright 2022 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package stats
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/julieqiu/github/internal/client"
+	"github.com/julieqiu/github/internal/reconcile"
+)
+
+// A Report holds statistics about the vulndb issues and the GitHub security
+// advisories that affect Go.
+type Report struct {
+	GeneratedAt time.Time
+	// Issues counts all issues; Malformed is the number of issues that could
+	// not be parsed, which are not counted elsewhere.
+	Issues    Count
+	Malformed int
+	// StdLib and ThirdParty split Issues by whether the issue is for the
+	// standard library.
+	StdLib     Count
+	ThirdParty Count
+	// ByStatus counts the issues with each lifecycle status, in lifecycle
+	// order; see client.Status.
+	ByStatus []*Count
+	// ByLabel counts the issues with each label, sorted by label.
+	ByLabel []*Count
+	// ByHost counts the issues by the host of their module path, such as
+	// "github.com", sorted by decreasing total. Standard library issues are
+	// counted under "std".
+	ByHost []*Count
+	// GHSAs is the number of advisories, and CoveredGHSAs the number of them
+	// that are tracked by an issue; see reconcile.Reconcile.
+	GHSAs        int
+	CoveredGHSAs int
+	// Timelines summarize how long issues take to triage, close and publish.
+	Timelines []*DurationSummary
+}
+
+// A Count is a number of issues, split by state.
+type Count struct {
+	// Name is the label or host being counted. It is empty for the totals.
+	Name   string `json:",omitempty"`
+	Total  int
+	Open   int
+	Closed int
+}
+
+func (c *Count) add(i *client.Issue) {
+	c.Total++
+	if i.Open {
+		c.Open++
+	} else {
+		c.Closed++
+	}
+}
+
+// Compute returns a Report for the given issues and advisories. The
+// statuses and timelines of the issues use the given label names.
+func Compute(issues []*client.Issue, malformed []*client.MalformedIssue, ghsas []*client.SecurityAdvisory, names client.LabelNames) *Report {
+	names = names.WithDefaults()
+	r := &Report{
+		GeneratedAt: time.Now(),
+		Malformed:   len(malformed),
+		GHSAs:       len(ghsas),
+	}
+	statuses := map[client.Status]*Count{}
+	for _, st := range client.Statuses {
+		c := &Count{Name: string(st)}
+		statuses[st] = c
+		r.ByStatus = append(r.ByStatus, c)
+	}
+	labels := map[string]*Count{}
+	hosts := map[string]*Count{}
+	for _, i := range issues {
+		r.Issues.add(i)
+		statuses[i.StatusUsing(names)].add(i)
+		if i.IsStdLib {
+			r.StdLib.add(i)
+		} else {
+			r.ThirdParty.add(i)
+		}
+		for l := range i.Labels {
+			if labels[l] == nil {
+				labels[l] = &Count{Name: l}
+			}
+			labels[l].add(i)
+		}
+		h := moduleHost(i)
+		if hosts[h] == nil {
+			hosts[h] = &Count{Name: h}
+		}
+		hosts[h].add(i)
+	}
+	for _, c := range labels {
+		r.ByLabel = append(r.ByLabel, c)
+	}
+	sort.Slice(r.ByLabel, func(i, j int) bool { return r.ByLabel[i].Name < r.ByLabel[j].Name })
+	for _, c := range hosts {
+		r.ByHost = append(r.ByHost, c)
+	}
+	sort.Slice(r.ByHost, func(i, j int) bool {
+		if r.ByHost[i].Total != r.ByHost[j].Total {
+			return r.ByHost[i].Total > r.ByHost[j].Total
+		}
+		return r.ByHost[i].Name < r.ByHost[j].Name
+	})
+	r.CoveredGHSAs = len(reconcile.Reconcile(issues, ghsas, nil).Covered)
+	r.Timelines = TimelineSummaries(issues, names)
+	return r
+}
+
+// moduleHost returns the first element of the issue's module path, or "std"
+// for the standard library.
+func moduleHost(i *client.Issue) string {
+	if i.IsStdLib {
+		return "std"
+	}
+	host, _, _ := strings.Cut(i.ModulePath, "/")
+	return host
+}
+
+// Formats are the output formats supported by Report.Write.
+var Formats = []string{"text", "json", "markdown"}
+
+// Write writes the report to w in the given format, one of Formats.
+func (r *Report) Write(w io.Writer, format string) error {
+	switch format {
+	case "text":
+		return r.writeText(w)
+	case "json":
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(r)
+	case "markdown":
+		return r.writeMarkdown(w)
+	default:
+		return fmt.Errorf("unknown format %q (want one of %s)", format, strings.Join(Formats, ", "))
+	}
+}
+
+func (r *Report) coverage() string {
+	if r.GHSAs == 0 {
+		return "-"
+	}
+	return fmt.Sprintf("%.1f%%", 100*float64(r.CoveredGHSAs)/float64(r.GHSAs))
+}
+
+func (r *Report) writeText(w io.Writer) error {
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+	fmt.Fprintf(tw, "Vulndb stats as of %s\n\n", r.GeneratedAt.Format("2006-01-02 15:04"))
+	fmt.Fprintf(tw, "Issues\tTotal\tOpen\tClosed\n")
+	for _, c := range []struct {
+		name string
+		c    Count
+	}{{"all", r.Issues}, {"stdlib", r.StdLib}, {"third party", r.ThirdParty}} {
+		fmt.Fprintf(tw, "%s\t%d\t%d\t%d\n", c.name, c.c.Total, c.c.Open, c.c.Closed)
+	}
+	fmt.Fprintf(tw, "malformed\t%d\t\t\n", r.Malformed)
+	writeCounts := func(title string, cs []*Count) {
+		fmt.Fprintf(tw, "\n%s\tTotal\tOpen\tClosed\n", title)
+		for _, c := range cs {
+			fmt.Fprintf(tw, "%s\t%d\t%d\t%d\n", c.Name, c.Total, c.Open, c.Closed)
+		}
+	}
+	writeCounts("Status", r.ByStatus)
+	writeCounts("Label", r.ByLabel)
+	writeCounts("Host", r.ByHost)
+	fmt.Fprintf(tw, "\nGHSAs\t%d\n", r.GHSAs)
+	fmt.Fprintf(tw, "covered by an issue\t%d (%s)\n", r.CoveredGHSAs, r.coverage())
+	fmt.Fprintf(tw, "\nDuration\tIssues\tp50\tp90\tp99\tmax\n")
+	for _, s := range r.Timelines {
+		fmt.Fprintf(tw, "%s\t%d\t%s\t%s\t%s\t%s\n", s.Name, s.Count,
+			FormatDuration(s.P50), FormatDuration(s.P90), FormatDuration(s.P99), FormatDuration(s.Max))
+	}
+	return tw.Flush()
+}
+
+func (r *Report) writeMarkdown(w io.Writer) error {
+	var b strings.Builder
+	fmt.Fprintf(&b, "## Vulndb stats as of %s\n\n", r.GeneratedAt.Format("2006-01-02"))
+	fmt.Fprintf(&b, "| Issues | Total | Open | Closed |\n|---|--:|--:|--:|\n")
+	fmt.Fprintf(&b, "| all | %d | %d | %d |\n", r.Issues.Total, r.Issues.Open, r.Issues.Closed)
+	fmt.Fprintf(&b, "| stdlib | %d | %d | %d |\n", r.StdLib.Total, r.StdLib.Open, r.StdLib.Closed)
+	fmt.Fprintf(&b, "| third party | %d | %d | %d |\n", r.ThirdParty.Total, r.ThirdParty.Open, r.ThirdParty.Closed)
+	fmt.Fprintf(&b, "| malformed | %d | | |\n", r.Malformed)
+	writeCounts := func(title string, cs []*Count) {
+		fmt.Fprintf(&b, "\n| %s | Total | Open | Closed |\n|---|--:|--:|--:|\n", title)
+		for _, c := range cs {
+			fmt.Fprintf(&b, "| %s | %d | %d | %d |\n", markdownEscape(c.Name), c.Total, c.Open, c.Closed)
+		}
+	}
+	writeCounts("Status", r.ByStatus)
+	writeCounts("Label", r.ByLabel)
+	writeCounts("Host", r.ByHost)
+	fmt.Fprintf(&b, "\n%d of %d GHSAs (%s) are covered by an issue.\n", r.CoveredGHSAs, r.GHSAs, r.coverage())
+	fmt.Fprintf(&b, "\n| Duration | Issues | p50 | p90 | p99 | max |\n|---|--:|--:|--:|--:|--:|\n")
+	for _, s := range r.Timelines {
+		fmt.Fprintf(&b, "| %s | %d | %s | %s | %s | %s |\n", markdownEscape(s.Name), s.Count,
+			FormatDuration(s.P50), FormatDuration(s.P90), FormatDuration(s.P99), FormatDuration(s.Max))
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// markdownEscape escapes the characters that would break a table cell.
+func markdownEscape(s string) string {
+	return strings.NewReplacer("|", `\|`, "*", `\*`, "_", `\_`).Replace(s)
+}
@@ -0,0 +1,117 @@
+// Copyright 2022 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package stats
+
+import (
+	"bytes"
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/julieqiu/github/internal/client"
+	"golang.org/x/vuln/osv"
+)
+
+var update = flag.Bool("update", false, "update the golden files in testdata")
+
+// testReport returns a Report for a small set of issues and advisories.
+func testReport() *Report {
+	day := func(d int) time.Time { return time.Date(2022, 3, d, 0, 0, 0, 0, time.UTC) }
+	labels := func(ls ...string) map[string]bool {
+		m := map[string]bool{}
+		for _, l := range ls {
+			m[l] = true
+		}
+		return m
+	}
+	issues := []*client.Issue{
+		{
+			Number: 140, ModulePath: "github.com/a/b", Aliases: []string{"CVE-2022-0001"},
+			Labels: labels("needs|report"), CreatedAt: day(1), ClosedAt: day(11),
+			Events: []*client.IssueEvent{{Type: "labeled", Label: "needs|report", CreatedAt: day(2)}},
+			OSV:    &osv.Entry{ID: "GO-2022-0140", Published: day(4)}, HasReport: true,
+		},
+		{
+			Number: 141, ModulePath: "net/http", IsStdLib: true, Open: true,
+			Labels: labels("stdlib"), CreatedAt: day(1),
+			Events: []*client.IssueEvent{{Type: "labeled", Label: "stdlib", CreatedAt: day(1).Add(6 * time.Hour)}},
+		},
+		{
+			Number: 142, ModulePath: "github.com/c/d", Open: true, CreatedAt: day(3),
+			PullRequests: []int{150},
+		},
+		{
+			Number: 143, ModulePath: "gitlab.com/e/f", Labels: labels("duplicate"),
+			CreatedAt: day(3), ClosedAt: day(5),
+		},
+	}
+	malformed := []*client.MalformedIssue{{Number: 144}}
+	ghsas := []*client.SecurityAdvisory{
+		{ID: "GHSA-8r3f-844c-mc37", Identifiers: []client.Identifier{{Type: "CVE", Value: "CVE-2022-0001"}}},
+		{ID: "GHSA-vp56-6g26-6827"},
+		{ID: "GHSA-q3j5-32m5-58c2"},
+	}
+	r := Compute(issues, malformed, ghsas, client.LabelNames{NeedsReport: "needs|report"})
+	r.GeneratedAt = time.Date(2022, 10, 1, 12, 0, 0, 0, time.UTC)
+	return r
+}
+
+func TestCompute(t *testing.T) {
+	r := testReport()
+	if r.Issues != (Count{Total: 4, Open: 2, Closed: 2}) || r.Malformed != 1 ||
+		r.StdLib != (Count{Total: 1, Open: 1}) || r.ThirdParty != (Count{Total: 3, Open: 1, Closed: 2}) {
+		t.Errorf("got counts %+v, %d malformed, stdlib %+v, third party %+v", r.Issues, r.Malformed, r.StdLib, r.ThirdParty)
+	}
+	var got []string
+	for _, c := range r.ByStatus {
+		if c.Total > 0 {
+			got = append(got, c.Name)
+		}
+	}
+	if want := "triaged report-in-review published duplicate"; strings.Join(got, " ") != want {
+		t.Errorf("statuses = %q, want %q", strings.Join(got, " "), want)
+	}
+	if r.ByHost[0].Name != "github.com" || r.ByHost[0].Total != 2 {
+		t.Errorf("first host = %+v, want github.com with 2 issues", r.ByHost[0])
+	}
+	if r.GHSAs != 3 || r.CoveredGHSAs != 1 {
+		t.Errorf("GHSAs = %d, covered %d; want 3, 1", r.GHSAs, r.CoveredGHSAs)
+	}
+	if s := r.Timelines[2]; s.Name != "needs|report → report published" || s.Count != 1 || s.Max != 48*time.Hour {
+		t.Errorf("publish timeline = %+v", s)
+	}
+}
+
+func TestWrite(t *testing.T) {
+	r := testReport()
+	for _, format := range Formats {
+		t.Run(format, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := r.Write(&buf, format); err != nil {
+				t.Fatal(err)
+			}
+			filename := filepath.Join("testdata", "report."+format+".golden")
+			if *update {
+				if err := os.WriteFile(filename, buf.Bytes(), 0644); err != nil {
+					t.Fatal(err)
+				}
+				return
+			}
+			want, err := os.ReadFile(filename)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(buf.Bytes(), want) {
+				t.Errorf("%s differs; run go test -update and check the diff:\n%s", filename, buf.Bytes())
+			}
+		})
+	}
+	if err := r.Write(&bytes.Buffer{}, "html"); err == nil {
+		t.Error("Write accepted an unknown format")
+	}
+}
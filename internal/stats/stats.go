@@ -1,13 +1,105 @@
+// Copyright 2022 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package stats computes statistics about the vulndb issues and GitHub
+// security advisories.
 package stats
 
 import (
 	"context"
+	"fmt"
+	"io"
+	"time"
 
+	"github.com/julieqiu/derrors"
 	"github.com/julieqiu/github/internal/client"
-	"github.com/julieqiu/github/internal/colly"
+	"golang.org/x/sync/errgroup"
+	vulnc "golang.org/x/vuln/client"
 )
 
-func Stats(ctx context.Context, githubClient *client.Client, collyClient *colly.Client) error {
-	collyClient.ReleaseNotes()
-	return nil
+// Stats computes a Report from the vulndb issues, the GitHub security
+// advisories and the vulndb reports, and writes it to w in the given format,
+// one of Formats. The triage labels have the given names.
+func Stats(ctx context.Context, githubClient *client.Client, db vulnc.Client, w io.Writer, format string, names client.LabelNames) (err error) {
+	defer derrors.Wrap(&err, "Stats")
+
+	issues, malformed, err := githubClient.ListByRepo(ctx)
+	if err != nil {
+		return err
+	}
+	ghsas, err := githubClient.ListGHSAs(ctx, time.Time{})
+	if err != nil {
+		return err
+	}
+	if err := attachEvents(ctx, githubClient, issues); err != nil {
+		return err
+	}
+	if err := attachReports(ctx, db, issues); err != nil {
+		return err
+	}
+	return Compute(issues, malformed, ghsas, names).Write(w, format)
+}
+
+// attachEvents fetches the events of each issue.
+func attachEvents(ctx context.Context, c *client.Client, issues []*client.Issue) error {
+	g, ctx := errgroup.WithContext(ctx)
+	g.SetLimit(10)
+	for _, i := range issues {
+		i := i
+		g.Go(func() error {
+			events, err := c.ListIssueEvents(ctx, i.Number)
+			if err != nil {
+				return err
+			}
+			i.Events = events
+			return nil
+		})
+	}
+	return g.Wait()
+}
+
+// attachReports attaches each vulndb report to the issue it was created for.
+func attachReports(ctx context.Context, db vulnc.Client, issues []*client.Issue) error {
+	byNumber := map[int]*client.Issue{}
+	for _, i := range issues {
+		byNumber[i.Number] = i
+	}
+	ids, err := db.ListIDs(ctx)
+	if err != nil {
+		return err
+	}
+	g, ctx := errgroup.WithContext(ctx)
+	g.SetLimit(10)
+	for _, id := range ids {
+		n, err := client.ReportIssueNumber(id)
+		if err != nil {
+			continue
+		}
+		i, ok := byNumber[n]
+		if !ok {
+			continue
+		}
+		id := id
+		g.Go(func() error {
+			e, err := db.GetByID(ctx, id)
+			if err != nil {
+				return err
+			}
+			i.SetReport(e)
+			return nil
+		})
+	}
+	return g.Wait()
+}
+
+// FormatDuration formats d in days, or hours if it is less than a day.
+func FormatDuration(d time.Duration) string {
+	if d == 0 {
+		return "-"
+	}
+	if d < 24*time.Hour {
+		return fmt.Sprintf("%.1fh", d.Hours())
+	}
+	return fmt.Sprintf("%.1fd", d.Hours()/24)
 }
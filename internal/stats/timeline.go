@@ -0,0 +1,144 @@
+// Copyright 2022 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package stats
+
+import (
+	"sort"
+	"time"
+
+	"github.com/julieqiu/github/internal/client"
+)
+
+// An IssueTimeline records when the milestones in the triage of an issue
+// were reached. A zero time means the milestone has not been reached.
+type IssueTimeline struct {
+	Number  int
+	Created time.Time
+	// FirstLabeled is when the first label was added.
+	FirstLabeled time.Time
+	// Closed is when the issue was last closed, if it is closed now.
+	Closed time.Time
+	// NeedsReport is when the NeedsReport label, or the label named for it,
+	// was first added.
+	NeedsReport time.Time
+	// Published is when the issue's vulndb report was published.
+	Published time.Time
+}
+
+// NewIssueTimeline returns the timeline of an issue, derived from its events
+// and its vulndb report, if one is attached. The NeedsReport label is read
+// from names.
+func NewIssueTimeline(i *client.Issue, names client.LabelNames) *IssueTimeline {
+	names = names.WithDefaults()
+	t := &IssueTimeline{Number: i.Number, Created: i.CreatedAt}
+	for _, e := range i.Events {
+		switch e.Type {
+		case "labeled":
+			if t.FirstLabeled.IsZero() {
+				t.FirstLabeled = e.CreatedAt
+			}
+			if e.Label == names.NeedsReport && t.NeedsReport.IsZero() {
+				t.NeedsReport = e.CreatedAt
+			}
+		case "closed":
+			t.Closed = e.CreatedAt
+		}
+	}
+	if i.Open {
+		t.Closed = time.Time{}
+	} else if t.Closed.IsZero() {
+		// The events may not have been fetched.
+		t.Closed = i.ClosedAt
+	}
+	if i.OSV != nil {
+		t.Published = i.OSV.Published
+	}
+	return t
+}
+
+// TimeToTriage returns the time from the creation of the issue to its first
+// label, and whether it has been labeled.
+func (t *IssueTimeline) TimeToTriage() (time.Duration, bool) {
+	return between(t.Created, t.FirstLabeled)
+}
+
+// TimeToClose returns the time from the creation of the issue until it was
+// closed, and whether it is closed.
+func (t *IssueTimeline) TimeToClose() (time.Duration, bool) {
+	return between(t.Created, t.Closed)
+}
+
+// TimeToPublish returns the time from when the issue was labeled NeedsReport
+// until its report was published, and whether both have happened. Reports
+// published before the label was added are not counted.
+func (t *IssueTimeline) TimeToPublish() (time.Duration, bool) {
+	return between(t.NeedsReport, t.Published)
+}
+
+func between(start, end time.Time) (time.Duration, bool) {
+	if start.IsZero() || end.IsZero() || end.Before(start) {
+		return 0, false
+	}
+	return end.Sub(start), true
+}
+
+// A DurationSummary describes the distribution of a set of durations.
+type DurationSummary struct {
+	Name  string
+	Count int
+	P50   time.Duration
+	P90   time.Duration
+	P99   time.Duration
+	Max   time.Duration
+}
+
+// Summarize returns a summary of ds. The percentiles use the nearest-rank
+// method, and are zero if ds is empty.
+func Summarize(name string, ds []time.Duration) *DurationSummary {
+	s := &DurationSummary{Name: name, Count: len(ds)}
+	if len(ds) == 0 {
+		return s
+	}
+	sorted := append([]time.Duration(nil), ds...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	s.P50 = percentile(sorted, 50)
+	s.P90 = percentile(sorted, 90)
+	s.P99 = percentile(sorted, 99)
+	s.Max = sorted[len(sorted)-1]
+	return s
+}
+
+// percentile returns the p-th percentile of the non-empty sorted slice ds.
+func percentile(ds []time.Duration, p int) time.Duration {
+	rank := (p*len(ds) + 99) / 100 // ceil(p/100 * n)
+	if rank < 1 {
+		rank = 1
+	}
+	return ds[rank-1]
+}
+
+// TimelineSummaries returns summaries of the time to triage, close and
+// publish a report for the given issues, whose labels have the given names.
+func TimelineSummaries(issues []*client.Issue, names client.LabelNames) []*DurationSummary {
+	names = names.WithDefaults()
+	var triage, closing, publish []time.Duration
+	for _, i := range issues {
+		t := NewIssueTimeline(i, names)
+		if d, ok := t.TimeToTriage(); ok {
+			triage = append(triage, d)
+		}
+		if d, ok := t.TimeToClose(); ok {
+			closing = append(closing, d)
+		}
+		if d, ok := t.TimeToPublish(); ok {
+			publish = append(publish, d)
+		}
+	}
+	return []*DurationSummary{
+		Summarize("created → first label", triage),
+		Summarize("created → closed", closing),
+		Summarize(names.NeedsReport+" → report published", publish),
+	}
+}
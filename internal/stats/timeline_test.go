@@ -0,0 +1,134 @@
+// Copyright 2022 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package stats
+
+import (
+	"testing"
+	"time"
+
+	"github.com/julieqiu/github/internal/client"
+	"golang.org/x/vuln/osv"
+)
+
+func hours(hs ...int) []time.Duration {
+	var ds []time.Duration
+	for _, h := range hs {
+		ds = append(ds, time.Duration(h)*time.Hour)
+	}
+	return ds
+}
+
+func TestSummarize(t *testing.T) {
+	for _, test := range []struct {
+		name string
+		in   []time.Duration
+		// want are the p50, p90, p99 and max, in hours.
+		want [4]int
+	}{
+		{"empty", nil, [4]int{0, 0, 0, 0}},
+		{"one", hours(5), [4]int{5, 5, 5, 5}},
+		// p50: rank ceil(0.5*2) = 1; p90: rank ceil(1.8) = 2.
+		{"two", hours(3, 1), [4]int{1, 3, 3, 3}},
+		// p50: rank 2; p90: rank ceil(3.6) = 4.
+		{"four", hours(4, 2, 1, 3), [4]int{2, 4, 4, 4}},
+		// p50: rank 5; p90: rank 9; p99: rank ceil(9.9) = 10.
+		{"ten", hours(7, 1, 10, 3, 5, 2, 9, 4, 8, 6), [4]int{5, 9, 10, 10}},
+		// p50: rank 50; p90: rank 90; p99: rank 99.
+		{"hundred", hours(seq(100)...), [4]int{50, 90, 99, 100}},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			s := Summarize("d", test.in)
+			got := [4]int{int(s.P50.Hours()), int(s.P90.Hours()), int(s.P99.Hours()), int(s.Max.Hours())}
+			if got != test.want || s.Count != len(test.in) || s.Name != "d" {
+				t.Errorf("got %+v, want percentiles %v of %d", s, test.want, len(test.in))
+			}
+		})
+	}
+}
+
+// seq returns the integers from 1 to n.
+func seq(n int) []int {
+	var out []int
+	for k := 1; k <= n; k++ {
+		out = append(out, k)
+	}
+	return out
+}
+
+func TestNewIssueTimeline(t *testing.T) {
+	day := func(d int) time.Time { return time.Date(2022, 3, d, 0, 0, 0, 0, time.UTC) }
+	names := client.LabelNames{NeedsReport: "report"}
+	for _, test := range []struct {
+		name  string
+		issue *client.Issue
+		// want are the times to triage, close and publish, in days, or -1
+		// if they have not happened.
+		want [3]int
+	}{
+		{
+			name:  "new",
+			issue: &client.Issue{Open: true, CreatedAt: day(1)},
+			want:  [3]int{-1, -1, -1},
+		},
+		{
+			name: "published",
+			issue: &client.Issue{
+				CreatedAt: day(1),
+				Events: []*client.IssueEvent{
+					{Type: "labeled", Label: "x", CreatedAt: day(2)},
+					{Type: "labeled", Label: "report", CreatedAt: day(4)},
+					{Type: "labeled", Label: "report", CreatedAt: day(5)},
+					{Type: "closed", CreatedAt: day(6)},
+					{Type: "reopened", CreatedAt: day(7)},
+					{Type: "closed", CreatedAt: day(9)},
+				},
+				OSV: &osv.Entry{Published: day(10)},
+			},
+			want: [3]int{1, 8, 6},
+		},
+		{
+			name: "default label name",
+			issue: &client.Issue{
+				CreatedAt: day(1),
+				Events:    []*client.IssueEvent{{Type: "labeled", Label: client.DefaultLabelNames.NeedsReport, CreatedAt: day(3)}},
+				OSV:       &osv.Entry{Published: day(10)},
+				ClosedAt:  day(4),
+			},
+			want: [3]int{2, 3, -1},
+		},
+		{
+			name: "reopened",
+			issue: &client.Issue{
+				Open:      true,
+				CreatedAt: day(1),
+				Events:    []*client.IssueEvent{{Type: "closed", CreatedAt: day(2)}, {Type: "reopened", CreatedAt: day(3)}},
+			},
+			want: [3]int{-1, -1, -1},
+		},
+		{
+			name: "published before labeled",
+			issue: &client.Issue{
+				CreatedAt: day(1),
+				Events:    []*client.IssueEvent{{Type: "labeled", Label: "report", CreatedAt: day(5)}},
+				OSV:       &osv.Entry{Published: day(3)},
+			},
+			want: [3]int{4, -1, -1},
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			tl := NewIssueTimeline(test.issue, names)
+			var got [3]int
+			for k, f := range []func() (time.Duration, bool){tl.TimeToTriage, tl.TimeToClose, tl.TimeToPublish} {
+				got[k] = -1
+				if d, ok := f(); ok {
+					got[k] = int(d.Hours() / 24)
+				}
+			}
+			if got != test.want {
+				t.Errorf("got %v, want %v", got, test.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,232 @@
+// Copyright 2022 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package store provides a file-backed store for the issues, GitHub security
+// advisories and vulndb entries shown on the dashboard.
+package store
+
+import (
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/julieqiu/derrors"
+	"github.com/julieqiu/github/internal/client"
+	"golang.org/x/vuln/osv"
+)
+
+// A Store holds the last-synced copy of the data from GitHub and the vulndb.
+// It is safe for concurrent use.
+type Store struct {
+	filename string
+
+	// These serialize syncs of each kind of data.
+	issuesMu, ghsasMu, entriesMu sync.Mutex
+
+	// saveMu serializes calls to Save, so that the last write always
+	// holds the most recent contents.
+	saveMu sync.Mutex
+
+	mu sync.Mutex
+	c  *contents
+}
+
+// formatVersion is the version of the on-disk format. It must be incremented
+// whenever the stored types, or the way they are derived from upstream data,
+// change. A store with a different version is discarded and synced again
+// from scratch.
+const formatVersion = 9
+
+// fullSyncInterval is how often all issues and GHSAs are listed, rather than
+// only those updated since the last sync. Issues that are deleted or
+// transferred to another repository, and GHSAs that are withdrawn or no
+// longer affect Go, are not listed as updated, so only a full listing
+// notices that they are gone.
+const fullSyncInterval = 24 * time.Hour
+
+// contents is the on-disk representation of a Store.
+type contents struct {
+	Version int
+
+	// IssuesSyncedAt is the time of the last successful sync of issues.
+	IssuesSyncedAt time.Time
+	// IssuesFullSyncedAt is the time of the last successful sync that
+	// listed all issues.
+	IssuesFullSyncedAt time.Time
+	// GHSAsSyncedAt is the time of the last successful sync of GHSAs.
+	GHSAsSyncedAt time.Time
+	// GHSAsFullSyncedAt is the time of the last successful sync that
+	// listed all GHSAs.
+	GHSAsFullSyncedAt time.Time
+	// DBModified is the last modified time of the vulndb at the last
+	// successful sync of entries.
+	DBModified time.Time
+
+	// Issues maps issue numbers to issues.
+	Issues map[int]*client.Issue
+	// Malformed maps issue numbers to issues that could not be parsed.
+	// An issue number is in at most one of Issues and Malformed.
+	Malformed map[int]*client.MalformedIssue
+	// PullRequests maps the numbers of the open pull requests to the
+	// pull requests. The PullRequests fields of the stored issues are
+	// not set; Issues derives them from these.
+	PullRequests map[int]*client.PullRequest
+	// GHSAs maps GHSA IDs to security advisories.
+	GHSAs map[string]*client.SecurityAdvisory
+	// Entries maps vulndb IDs to entries.
+	Entries map[string]*osv.Entry
+}
+
+func newContents() *contents {
+	return &contents{
+		Version:      formatVersion,
+		Issues:       map[int]*client.Issue{},
+		Malformed:    map[int]*client.MalformedIssue{},
+		PullRequests: map[int]*client.PullRequest{},
+		GHSAs:        map[string]*client.SecurityAdvisory{},
+		Entries:      map[string]*osv.Entry{},
+	}
+}
+
+// Open returns a Store backed by filename. If the file does not exist, the
+// Store starts out empty and the file is created on the first Save.
+func Open(filename string) (_ *Store, err error) {
+	defer derrors.Wrap(&err, "store.Open(%q)", filename)
+
+	s := &Store{filename: filename, c: newContents()}
+	data, err := os.ReadFile(filename)
+	if errors.Is(err, fs.ErrNotExist) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, s.c); err != nil {
+		return nil, err
+	}
+	if s.c.Version != formatVersion {
+		s.c = newContents()
+		return s, nil
+	}
+	// Maps that were empty when saved are decoded as nil.
+	if s.c.Issues == nil {
+		s.c.Issues = map[int]*client.Issue{}
+	}
+	if s.c.Malformed == nil {
+		s.c.Malformed = map[int]*client.MalformedIssue{}
+	}
+	if s.c.PullRequests == nil {
+		s.c.PullRequests = map[int]*client.PullRequest{}
+	}
+	if s.c.GHSAs == nil {
+		s.c.GHSAs = map[string]*client.SecurityAdvisory{}
+	}
+	if s.c.Entries == nil {
+		s.c.Entries = map[string]*osv.Entry{}
+	}
+	return s, nil
+}
+
+// Save writes the contents of the store to its file.
+func (s *Store) Save() (err error) {
+	defer derrors.Wrap(&err, "Save()")
+
+	s.saveMu.Lock()
+	defer s.saveMu.Unlock()
+	s.mu.Lock()
+	data, err := json.Marshal(s.c)
+	s.mu.Unlock()
+	if err != nil {
+		return err
+	}
+	// Write to a temporary file and rename it, so that a crash never leaves
+	// a partially written store behind.
+	f, err := os.CreateTemp(filepath.Dir(s.filename), filepath.Base(s.filename)+".tmp*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	return os.Rename(f.Name(), s.filename)
+}
+
+// Issues returns the stored issues, sorted by number, with the open pull
+// requests that mention them.
+// The issues are copies, so callers may modify them.
+func (s *Store) Issues() []*client.Issue {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var out []*client.Issue
+	for _, i := range s.c.Issues {
+		i2 := *i
+		out = append(out, &i2)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		return out[i].Number < out[j].Number
+	})
+	var prs []*client.PullRequest
+	for _, pr := range s.c.PullRequests {
+		prs = append(prs, pr)
+	}
+	client.SetPullRequests(out, prs)
+	return out
+}
+
+// Malformed returns the stored issues that could not be parsed, sorted by
+// number.
+func (s *Store) Malformed() []*client.MalformedIssue {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var out []*client.MalformedIssue
+	for _, m := range s.c.Malformed {
+		out = append(out, m)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		return out[i].Number < out[j].Number
+	})
+	return out
+}
+
+// GHSAs returns the stored security advisories, sorted by ID.
+func (s *Store) GHSAs() []*client.SecurityAdvisory {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var out []*client.SecurityAdvisory
+	for _, sa := range s.c.GHSAs {
+		out = append(out, sa)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		return out[i].PrettyID() < out[j].PrettyID()
+	})
+	return out
+}
+
+// Entries returns the stored vulndb entries, sorted by ID.
+func (s *Store) Entries() []*osv.Entry {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var out []*osv.Entry
+	for _, e := range s.c.Entries {
+		out = append(out, e)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		return out[i].ID < out[j].ID
+	})
+	return out
+}
@@ -0,0 +1,261 @@
+// Copyright 2022 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package store
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/julieqiu/derrors"
+	"github.com/julieqiu/github/internal/client"
+	"golang.org/x/sync/errgroup"
+	vulnc "golang.org/x/vuln/client"
+	"golang.org/x/vuln/osv"
+)
+
+// An IssueLister lists the issues and pull requests updated since a given
+// time, and the events of an issue.
+// It is implemented by *client.Client.
+type IssueLister interface {
+	ListIssuesAndPRsSince(ctx context.Context, since time.Time) ([]*client.Issue, []*client.MalformedIssue, []*client.PullRequest, error)
+	ListIssueEvents(ctx context.Context, number int) ([]*client.IssueEvent, error)
+}
+
+// A GHSALister lists the security advisories updated since a given time.
+// It is implemented by *client.Client.
+type GHSALister interface {
+	ListGHSAs(ctx context.Context, since time.Time) ([]*client.SecurityAdvisory, error)
+}
+
+// A Source serves the contents of a Store, syncing each kind of data from
+// upstream before returning it.
+//
+// Only issues, pull requests and GHSAs that were updated since the last
+// successful sync are fetched, except that all issues and GHSAs are listed
+// once a day, so that those that are gone upstream are dropped. Vulndb
+// entries are fetched again only when the database has been modified since
+// the last successful sync.
+type Source struct {
+	st     *Store
+	issues IssueLister
+	ghsas  GHSALister
+	db     vulnc.Client
+}
+
+// Source returns a Source that syncs s from the given upstreams.
+func (s *Store) Source(issues IssueLister, ghsas GHSALister, db vulnc.Client) *Source {
+	return &Source{st: s, issues: issues, ghsas: ghsas, db: db}
+}
+
+// ListIssues syncs the issues updated since the last successful sync, saves
+// the store and returns all stored issues, and those that could not be
+// parsed.
+func (src *Source) ListIssues(ctx context.Context) (_ []*client.Issue, _ []*client.MalformedIssue, err error) {
+	defer derrors.Wrap(&err, "ListIssues")
+	if err := src.syncIssues(ctx); err != nil {
+		return nil, nil, err
+	}
+	if err := src.st.Save(); err != nil {
+		return nil, nil, err
+	}
+	return src.st.Issues(), src.st.Malformed(), nil
+}
+
+// ListGHSAs syncs the GHSAs updated since the last successful sync, saves
+// the store and returns all stored GHSAs.
+func (src *Source) ListGHSAs(ctx context.Context) (_ []*client.SecurityAdvisory, err error) {
+	defer derrors.Wrap(&err, "ListGHSAs")
+	if err := src.syncGHSAs(ctx); err != nil {
+		return nil, err
+	}
+	if err := src.st.Save(); err != nil {
+		return nil, err
+	}
+	return src.st.GHSAs(), nil
+}
+
+// ListReports syncs the vulndb entries if the database was modified since
+// the last successful sync, saves the store and returns all stored entries.
+func (src *Source) ListReports(ctx context.Context) (_ []*osv.Entry, err error) {
+	defer derrors.Wrap(&err, "ListReports")
+	if err := src.syncEntries(ctx); err != nil {
+		return nil, err
+	}
+	if err := src.st.Save(); err != nil {
+		return nil, err
+	}
+	return src.st.Entries(), nil
+}
+
+func (src *Source) syncIssues(ctx context.Context) error {
+	s := src.st
+	defer lock(&s.issuesMu)()
+
+	s.mu.Lock()
+	since := s.c.IssuesSyncedAt
+	full := time.Since(s.c.IssuesFullSyncedAt) >= fullSyncInterval
+	s.mu.Unlock()
+
+	// Record the start time rather than the end time, so that issues
+	// updated while we are listing are picked up by the next sync.
+	start := time.Now()
+	listSince := since
+	if full {
+		listSince = time.Time{}
+	}
+	issues, malformed, prs, err := src.issues.ListIssuesAndPRsSince(ctx, listSince)
+	if err != nil {
+		return err
+	}
+	// Labeling, closing or assigning an issue changes its update time, so
+	// the events of the other issues are still current, and a full listing
+	// can reuse them.
+	oldEvents := map[int][]*client.IssueEvent{}
+	if full && !since.IsZero() {
+		s.mu.Lock()
+		for _, i := range s.c.Issues {
+			oldEvents[i.Number] = i.Events
+		}
+		s.mu.Unlock()
+	}
+	g, gctx := errgroup.WithContext(ctx)
+	g.SetLimit(10)
+	for _, i := range issues {
+		i := i
+		if events, ok := oldEvents[i.Number]; ok && i.UpdatedAt.Before(since) {
+			i.Events = events
+			continue
+		}
+		g.Go(func() error {
+			events, err := src.issues.ListIssueEvents(gctx, i.Number)
+			if err != nil {
+				return err
+			}
+			i.Events = events
+			return nil
+		})
+	}
+	if err := g.Wait(); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if full {
+		// Drop the issues that are no longer in the repository.
+		s.c.Issues = map[int]*client.Issue{}
+		s.c.Malformed = map[int]*client.MalformedIssue{}
+		s.c.PullRequests = map[int]*client.PullRequest{}
+		s.c.IssuesFullSyncedAt = start
+	}
+	// An issue that was malformed may have been fixed, and vice versa.
+	for _, i := range issues {
+		s.c.Issues[i.Number] = i
+		delete(s.c.Malformed, i.Number)
+	}
+	for _, m := range malformed {
+		s.c.Malformed[m.Number] = m
+		delete(s.c.Issues, m.Number)
+	}
+	// Pull requests in the listing were opened, closed or edited since the
+	// last sync; the others still link the same issues.
+	for _, pr := range prs {
+		if pr.Open {
+			s.c.PullRequests[pr.Number] = pr
+		} else {
+			delete(s.c.PullRequests, pr.Number)
+		}
+	}
+	s.c.IssuesSyncedAt = start
+	return nil
+}
+
+func (src *Source) syncGHSAs(ctx context.Context) error {
+	s := src.st
+	defer lock(&s.ghsasMu)()
+
+	s.mu.Lock()
+	since := s.c.GHSAsSyncedAt
+	full := time.Since(s.c.GHSAsFullSyncedAt) >= fullSyncInterval
+	s.mu.Unlock()
+
+	start := time.Now()
+	if full {
+		since = time.Time{}
+	}
+	sas, err := src.ghsas.ListGHSAs(ctx, since)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if full {
+		// Drop the GHSAs that were withdrawn or no longer affect Go.
+		s.c.GHSAs = map[string]*client.SecurityAdvisory{}
+		s.c.GHSAsFullSyncedAt = start
+	}
+	for _, sa := range sas {
+		s.c.GHSAs[sa.PrettyID()] = sa
+	}
+	s.c.GHSAsSyncedAt = start
+	return nil
+}
+
+func (src *Source) syncEntries(ctx context.Context) error {
+	s := src.st
+	defer lock(&s.entriesMu)()
+
+	modified, err := src.db.LastModifiedTime(ctx)
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	unchanged := !s.c.DBModified.IsZero() && !modified.After(s.c.DBModified)
+	s.mu.Unlock()
+	if unchanged {
+		return nil
+	}
+
+	ids, err := src.db.ListIDs(ctx)
+	if err != nil {
+		return err
+	}
+	entries := make([]*osv.Entry, len(ids))
+	g, ctx := errgroup.WithContext(ctx)
+	g.SetLimit(10)
+	for k, id := range ids {
+		k, id := k, id
+		g.Go(func() error {
+			e, err := src.db.GetByID(ctx, id)
+			if err != nil {
+				return err
+			}
+			entries[k] = e
+			return nil
+		})
+	}
+	if err := g.Wait(); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.c.Entries = map[string]*osv.Entry{}
+	for _, e := range entries {
+		if e != nil {
+			s.c.Entries[e.ID] = e
+		}
+	}
+	s.c.DBModified = modified
+	return nil
+}
+
+// lock locks mu and returns a function that unlocks it.
+func lock(mu *sync.Mutex) func() {
+	mu.Lock()
+	return mu.Unlock
+}
@@ -0,0 +1,259 @@
+// Copyright 2022 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package store
+
+import (
+	"context"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/julieqiu/github/internal/client"
+)
+
+// fakeIssues is an IssueLister that serves a fixed set of issues and pull
+// requests.
+type fakeIssues struct {
+	mu     sync.Mutex
+	issues map[int]*client.Issue
+	prs    map[int]*fakePR
+	// eventCalls counts the calls to ListIssueEvents, by issue number.
+	eventCalls map[int]int
+}
+
+type fakePR struct {
+	client.PullRequest
+	updated time.Time
+}
+
+func newFakeIssues(issues ...*client.Issue) *fakeIssues {
+	f := &fakeIssues{issues: map[int]*client.Issue{}, prs: map[int]*fakePR{}, eventCalls: map[int]int{}}
+	for _, i := range issues {
+		f.issues[i.Number] = i
+	}
+	return f
+}
+
+// setPR adds or updates a pull request that links the given issues.
+func (f *fakeIssues) setPR(number int, open bool, updated time.Time, linked ...int) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.prs[number] = &fakePR{client.PullRequest{Number: number, Open: open, LinkedIssues: linked}, updated}
+}
+
+func (f *fakeIssues) ListIssuesAndPRsSince(_ context.Context, since time.Time) ([]*client.Issue, []*client.MalformedIssue, []*client.PullRequest, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var out []*client.Issue
+	for _, i := range f.issues {
+		if !i.UpdatedAt.Before(since) {
+			i2 := *i
+			out = append(out, &i2)
+		}
+	}
+	var prs []*client.PullRequest
+	for _, pr := range f.prs {
+		if !pr.updated.Before(since) {
+			pr2 := pr.PullRequest
+			prs = append(prs, &pr2)
+		}
+	}
+	return out, nil, prs, nil
+}
+
+func (f *fakeIssues) ListIssueEvents(_ context.Context, number int) ([]*client.IssueEvent, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.eventCalls[number]++
+	return []*client.IssueEvent{{Type: "labeled", Label: "x"}}, nil
+}
+
+func numbers(issues []*client.Issue) []int {
+	var ns []int
+	for _, i := range issues {
+		ns = append(ns, i.Number)
+	}
+	sort.Ints(ns)
+	return ns
+}
+
+func sameInts(a, b []int) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for k := range a {
+		if a[k] != b[k] {
+			return false
+		}
+	}
+	return true
+}
+
+func TestSyncIssuesDropsDeleted(t *testing.T) {
+	ctx := context.Background()
+	st, err := Open(filepath.Join(t.TempDir(), "store.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	old := time.Now().Add(-time.Hour)
+	f := newFakeIssues(
+		&client.Issue{Number: 140, UpdatedAt: old},
+		&client.Issue{Number: 141, UpdatedAt: old},
+	)
+	src := st.Source(f, nil, nil)
+	check := func(want ...int) {
+		t.Helper()
+		issues, _, err := src.ListIssues(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := numbers(issues); !sameInts(got, want) {
+			t.Errorf("issues = %v, want %v", got, want)
+		}
+	}
+	check(140, 141)
+
+	// An incremental sync does not see that an issue is gone.
+	delete(f.issues, 141)
+	check(140, 141)
+
+	// A full sync does, and reuses the events of unchanged issues.
+	st.mu.Lock()
+	st.c.IssuesFullSyncedAt = time.Now().Add(-fullSyncInterval - time.Minute)
+	st.mu.Unlock()
+	check(140)
+	if got := f.eventCalls[140]; got != 1 {
+		t.Errorf("events of #140 listed %d times, want 1", got)
+	}
+	if issues := st.Issues(); len(issues) != 1 || len(issues[0].Events) != 1 {
+		t.Errorf("events of #140 were not kept: %+v", issues)
+	}
+}
+
+func TestSyncIssuesPullRequests(t *testing.T) {
+	ctx := context.Background()
+	st, err := Open(filepath.Join(t.TempDir(), "store.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	old := time.Now().Add(-time.Hour)
+	f := newFakeIssues(
+		&client.Issue{Number: 140, UpdatedAt: old},
+		&client.Issue{Number: 141, UpdatedAt: old},
+	)
+	f.setPR(150, true, old, 140)
+	f.setPR(151, false, old, 141)
+	src := st.Source(f, nil, nil)
+	check := func(want map[int][]int) {
+		t.Helper()
+		issues, _, err := src.ListIssues(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, i := range issues {
+			if got := i.PullRequests; !sameInts(got, want[i.Number]) {
+				t.Errorf("#%d: PullRequests = %v, want %v", i.Number, got, want[i.Number])
+			}
+		}
+	}
+	check(map[int][]int{140: {150}})
+
+	// An open pull request that is not in an incremental listing still
+	// links its issue, even when the issue itself is updated.
+	f.issues[140].UpdatedAt = time.Now()
+	check(map[int][]int{140: {150}})
+
+	// Pull requests that are opened or closed since the last sync add or
+	// remove their links.
+	f.setPR(150, false, time.Now(), 140)
+	f.setPR(152, true, time.Now(), 140, 141)
+	check(map[int][]int{140: {152}, 141: {152}})
+
+	// An edited pull request links the issues its body now mentions.
+	f.setPR(152, true, time.Now(), 141)
+	check(map[int][]int{141: {152}})
+
+	// A full sync keeps only the open pull requests.
+	f.setPR(153, true, old, 140)
+	st.mu.Lock()
+	st.c.IssuesFullSyncedAt = time.Time{}
+	st.mu.Unlock()
+	check(map[int][]int{140: {153}, 141: {152}})
+	st.mu.Lock()
+	n := len(st.c.PullRequests)
+	st.mu.Unlock()
+	if n != 2 {
+		t.Errorf("store has %d pull requests, want the 2 open ones", n)
+	}
+}
+
+// fakeGHSAs is a GHSALister that serves a fixed set of advisories.
+type fakeGHSAs struct {
+	sas []*client.SecurityAdvisory
+	// since is the argument of the last call to ListGHSAs.
+	since time.Time
+}
+
+func (f *fakeGHSAs) ListGHSAs(_ context.Context, since time.Time) ([]*client.SecurityAdvisory, error) {
+	f.since = since
+	var out []*client.SecurityAdvisory
+	for _, sa := range f.sas {
+		if !sa.UpdatedAt.Before(since) {
+			out = append(out, sa)
+		}
+	}
+	return out, nil
+}
+
+func TestSyncGHSAsDropsWithdrawn(t *testing.T) {
+	ctx := context.Background()
+	st, err := Open(filepath.Join(t.TempDir(), "store.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	old := time.Now().Add(-time.Hour)
+	ghsa := func(id string) *client.SecurityAdvisory {
+		return &client.SecurityAdvisory{
+			Identifiers: []client.Identifier{{Type: "GHSA", Value: id}},
+			UpdatedAt:   old,
+		}
+	}
+	f := &fakeGHSAs{sas: []*client.SecurityAdvisory{ghsa("GHSA-8r3f-844c-mc37"), ghsa("GHSA-vp56-6g26-6827")}}
+	src := st.Source(nil, f, nil)
+	check := func(want ...string) {
+		t.Helper()
+		sas, err := src.ListGHSAs(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var got []string
+		for _, sa := range sas {
+			got = append(got, sa.PrettyID())
+		}
+		if strings.Join(got, " ") != strings.Join(want, " ") {
+			t.Errorf("GHSAs = %v, want %v", got, want)
+		}
+	}
+	check("GHSA-8r3f-844c-mc37", "GHSA-vp56-6g26-6827")
+
+	// An incremental sync does not see that an advisory is gone.
+	f.sas = f.sas[:1]
+	check("GHSA-8r3f-844c-mc37", "GHSA-vp56-6g26-6827")
+	if f.since.IsZero() {
+		t.Error("second sync listed all GHSAs")
+	}
+
+	// A full sync does.
+	st.mu.Lock()
+	st.c.GHSAsFullSyncedAt = time.Now().Add(-fullSyncInterval - time.Minute)
+	st.mu.Unlock()
+	check("GHSA-8r3f-844c-mc37")
+	if !f.since.IsZero() {
+		t.Errorf("full sync listed GHSAs since %v", f.since)
+	}
+}
@@ -0,0 +1,419 @@
+// Copyright 2022 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package triage
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/julieqiu/github/internal/client"
+	"github.com/julieqiu/github/internal/ghsa"
+)
+
+// DuplicateRule is the rule ID of the findings made by DuplicateFindings.
+const DuplicateRule = "duplicate"
+
+// A DuplicateGroup is a set of issues that seem to be about the same
+// vulnerability.
+type DuplicateGroup struct {
+	// Canonical is the issue to keep: the one with a published report, or
+	// else the oldest one that is not labeled duplicate.
+	Canonical *client.Issue
+	// Duplicates are the other issues, by number.
+	Duplicates []*client.Issue
+	// Overlapping is set if the issues only have the same module, and
+	// advisories that affect overlapping versions of it. Such issues are
+	// often about separate vulnerabilities, so they need a closer look
+	// before being labeled. An issue in a group of duplicates appears in
+	// such a group only if it is the canonical issue of its group.
+	Overlapping bool
+	// Reasons say why the issues were grouped.
+	Reasons []string
+}
+
+// FindDuplicates groups the issues that seem to be duplicates of each other.
+// Two issues are grouped if
+//
+//   - they mention the same CVE or GHSA, in their titles or bodies or in
+//     their vulndb reports, or
+//   - they mention different IDs that are aliases of each other according
+//     to one of the advisories or to a vulndb report.
+//
+// Groups of issues, or single issues, for the same module whose advisories
+// affect overlapping versions of it are grouped in turn, by their canonical
+// issues, into groups marked Overlapping. Issues with no module path are not
+// grouped this way.
+//
+// The duplicate label is read from names. Groups whose duplicates are all
+// labeled already are not returned. The groups are ordered by the number
+// of their canonical issue, with groups of duplicates before overlapping
+// groups.
+func FindDuplicates(issues []*client.Issue, advisories []*client.SecurityAdvisory, names client.LabelNames) []*DuplicateGroup {
+	names = names.WithDefaults()
+	issues = append([]*client.Issue(nil), issues...)
+	sort.Slice(issues, func(i, j int) bool { return issues[i].Number < issues[j].Number })
+	ids := newUnionFind(len(issues))
+
+	// Issues that mention the same ID.
+	byID := map[string][]int{}
+	var allIDs []string
+	for k, i := range issues {
+		for _, id := range vulnIDs(i) {
+			if byID[id] == nil {
+				allIDs = append(allIDs, id)
+			}
+			byID[id] = append(byID[id], k)
+		}
+	}
+	for _, id := range allIDs {
+		ks := byID[id]
+		if len(ks) < 2 {
+			continue
+		}
+		var nums []string
+		for _, k := range ks {
+			nums = append(nums, fmt.Sprintf("#%d", issues[k].Number))
+		}
+		reason := fmt.Sprintf("%s and %s mention %s", strings.Join(nums[:len(nums)-1], ", "), nums[len(nums)-1], id)
+		for _, k := range ks[1:] {
+			ids.union(ks[0], k, reason)
+		}
+	}
+
+	// Issues that mention aliases of each other.
+	var aliasSets []aliasSet
+	for _, sa := range advisories {
+		s := aliasSet{source: sa.PrettyID()}
+		for _, id := range sa.Identifiers {
+			s.ids = append(s.ids, id.Value)
+		}
+		aliasSets = append(aliasSets, s)
+	}
+	for _, i := range issues {
+		if i.OSV != nil {
+			aliasSets = append(aliasSets, aliasSet{source: i.OSV.ID, ids: append([]string{i.OSV.ID}, i.OSV.Aliases...)})
+		}
+	}
+	for _, s := range aliasSets {
+		first := -1
+		for _, id := range s.ids {
+			for _, k := range byID[id] {
+				if first < 0 {
+					first = k
+					continue
+				}
+				if ids.find(first) != ids.find(k) {
+					ids.union(first, k, fmt.Sprintf("%s mention aliases in %s", pair(issues[first], issues[k]), s.source))
+				}
+			}
+		}
+	}
+
+	var groups []*DuplicateGroup
+	keep := func(g *DuplicateGroup) {
+		for _, d := range g.Duplicates {
+			if !d.Labels[names.Duplicate] {
+				groups = append(groups, g)
+				return
+			}
+		}
+	}
+	// canonical is the canonical issue of the group of each issue, or the
+	// issue itself if it is in no group.
+	canonical := make([]*client.Issue, len(issues))
+	for root, ks := range ids.sets() {
+		c := issues[ks[0]]
+		if len(ks) > 1 {
+			var is []*client.Issue
+			for _, k := range ks {
+				is = append(is, issues[k])
+			}
+			g := newDuplicateGroup(is, names)
+			g.Reasons = dedupe(ids.reasons[root])
+			keep(g)
+			c = g.Canonical
+		}
+		for _, k := range ks {
+			canonical[k] = c
+		}
+	}
+
+	// Issues for the same module whose advisories overlap, joined by
+	// the groups of duplicates they are in.
+	overlaps := newUnionFind(len(issues))
+	advisoriesOf := issueAdvisories(issues, advisories)
+	byModule := map[string][]int{}
+	var modules []string
+	for k, i := range issues {
+		if i.ModulePath == "" {
+			continue
+		}
+		if byModule[i.ModulePath] == nil {
+			modules = append(modules, i.ModulePath)
+		}
+		byModule[i.ModulePath] = append(byModule[i.ModulePath], k)
+	}
+	for _, mod := range modules {
+		ks := byModule[mod]
+		for x, k1 := range ks {
+			for _, k2 := range ks[x+1:] {
+				r1, r2 := ids.find(k1), ids.find(k2)
+				if r1 == r2 || overlaps.find(r1) == overlaps.find(r2) {
+					continue
+				}
+				if sa1, sa2 := overlapping(mod, advisoriesOf[k1], advisoriesOf[k2]); sa1 != nil {
+					overlaps.union(r1, r2, fmt.Sprintf("%s are for %s, and %s and %s affect overlapping versions",
+						pair(issues[k1], issues[k2]), mod, sa1.PrettyID(), sa2.PrettyID()))
+				}
+			}
+		}
+	}
+	for root, ks := range overlaps.sets() {
+		var is []*client.Issue
+		for _, k := range ks {
+			if ids.find(k) == k {
+				is = append(is, canonical[k])
+			}
+		}
+		if len(is) > 1 {
+			g := newDuplicateGroup(is, names)
+			g.Overlapping = true
+			g.Reasons = dedupe(overlaps.reasons[root])
+			keep(g)
+		}
+	}
+
+	sort.Slice(groups, func(i, j int) bool {
+		gi, gj := groups[i], groups[j]
+		if gi.Canonical.Number != gj.Canonical.Number {
+			return gi.Canonical.Number < gj.Canonical.Number
+		}
+		return !gi.Overlapping && gj.Overlapping
+	})
+	return groups
+}
+
+// A unionFind is a partition of the integers [0, n) into sets, with the
+// reasons that the members of each set were joined.
+type unionFind struct {
+	parent []int
+	// reasons are the reasons of each set, by its smallest member.
+	reasons map[int][]string
+}
+
+func newUnionFind(n int) *unionFind {
+	u := &unionFind{parent: make([]int, n), reasons: map[int][]string{}}
+	for k := range u.parent {
+		u.parent[k] = k
+	}
+	return u
+}
+
+// find returns the smallest member of the set of k.
+func (u *unionFind) find(k int) int {
+	if u.parent[k] != k {
+		u.parent[k] = u.find(u.parent[k])
+	}
+	return u.parent[k]
+}
+
+// union joins the sets of a and b, and adds reason to the reasons of the
+// joined set.
+func (u *unionFind) union(a, b int, reason string) {
+	ra, rb := u.find(a), u.find(b)
+	if ra > rb {
+		ra, rb = rb, ra
+	}
+	if ra != rb {
+		u.parent[rb] = ra
+		u.reasons[ra] = append(u.reasons[ra], u.reasons[rb]...)
+		delete(u.reasons, rb)
+	}
+	u.reasons[ra] = append(u.reasons[ra], reason)
+}
+
+// sets returns the members of each set, in increasing order, by the
+// smallest member.
+func (u *unionFind) sets() map[int][]int {
+	out := map[int][]int{}
+	for k := range u.parent {
+		out[u.find(k)] = append(out[u.find(k)], k)
+	}
+	return out
+}
+
+// An aliasSet is a set of IDs for one vulnerability, according to source.
+type aliasSet struct {
+	source string
+	ids    []string
+}
+
+// pair returns the numbers of two issues, in increasing order.
+func pair(a, b *client.Issue) string {
+	if a.Number > b.Number {
+		a, b = b, a
+	}
+	return fmt.Sprintf("#%d and #%d", a.Number, b.Number)
+}
+
+// issueAdvisories returns the advisories for the GHSAs and CVEs that each
+// issue mentions, by the index of the issue.
+func issueAdvisories(issues []*client.Issue, advisories []*client.SecurityAdvisory) [][]*client.SecurityAdvisory {
+	byID := map[string][]*client.SecurityAdvisory{}
+	for _, sa := range advisories {
+		byID[sa.PrettyID()] = append(byID[sa.PrettyID()], sa)
+		for _, id := range sa.Identifiers {
+			if id.Type == "CVE" {
+				byID[id.Value] = append(byID[id.Value], sa)
+			}
+		}
+	}
+	out := make([][]*client.SecurityAdvisory, len(issues))
+	for k, i := range issues {
+		seen := map[*client.SecurityAdvisory]bool{}
+		for _, id := range vulnIDs(i) {
+			for _, sa := range byID[id] {
+				if !seen[sa] {
+					seen[sa] = true
+					out[k] = append(out[k], sa)
+				}
+			}
+		}
+	}
+	return out
+}
+
+// overlapping returns an advisory from each list, different from each other,
+// that affect overlapping versions of the module, or nils if there are none.
+// Ranges that cannot be parsed are ignored.
+func overlapping(mod string, as, bs []*client.SecurityAdvisory) (_, _ *client.SecurityAdvisory) {
+	for _, a := range as {
+		for _, b := range bs {
+			if a == b {
+				continue
+			}
+			for _, ra := range moduleRanges(mod, a) {
+				for _, rb := range moduleRanges(mod, b) {
+					if ra.Overlaps(rb) {
+						return a, b
+					}
+				}
+			}
+		}
+	}
+	return nil, nil
+}
+
+// moduleRanges returns the vulnerable version ranges of the packages of the
+// advisory that are in the module.
+func moduleRanges(mod string, sa *client.SecurityAdvisory) []ghsa.VersionRange {
+	var rs []ghsa.VersionRange
+	for _, v := range sa.Vulns {
+		if v.Package != mod && !strings.HasPrefix(v.Package, mod+"/") {
+			continue
+		}
+		if r, err := ghsa.ParseVersionRange(v.VulnerableVersionRange); err == nil {
+			rs = append(rs, r)
+		}
+	}
+	return rs
+}
+
+// vulnIDs returns the CVE and GHSA IDs mentioned by the issue or its report.
+func vulnIDs(i *client.Issue) []string {
+	var all []string
+	all = append(all, i.Aliases...)
+	if i.OSV != nil {
+		all = append(all, i.OSV.Aliases...)
+	}
+	var ids []string
+	for _, id := range all {
+		if strings.HasPrefix(id, "CVE-") || strings.HasPrefix(id, "GHSA-") {
+			ids = append(ids, id)
+		}
+	}
+	return dedupe(ids)
+}
+
+// newDuplicateGroup returns the group of the issues, choosing its canonical
+// issue.
+func newDuplicateGroup(is []*client.Issue, names client.LabelNames) *DuplicateGroup {
+	sort.Slice(is, func(i, j int) bool { return is[i].Number < is[j].Number })
+	rank := func(i *client.Issue) int {
+		switch {
+		case i.HasReport:
+			return 0
+		case !i.Labels[names.Duplicate]:
+			return 1
+		default:
+			return 2
+		}
+	}
+	c := 0
+	for k, i := range is {
+		if rank(i) < rank(is[c]) {
+			c = k
+		}
+	}
+	g := &DuplicateGroup{Canonical: is[c]}
+	for k, i := range is {
+		if k != c {
+			g.Duplicates = append(g.Duplicates, i)
+		}
+	}
+	return g
+}
+
+// DuplicateFindings returns a finding for each duplicate in the groups that
+// is not labeled as one. Its fix adds the duplicate label, read from names,
+// and a comment linking the duplicate to the canonical issue, which GitHub
+// shows on both issues. The findings for overlapping groups are only hints,
+// with no fix.
+func DuplicateFindings(groups []*DuplicateGroup, names client.LabelNames) []*Finding {
+	names = names.WithDefaults()
+	var fs []*Finding
+	for _, g := range groups {
+		for _, d := range g.Duplicates {
+			if d.Labels[names.Duplicate] {
+				continue
+			}
+			if g.Overlapping {
+				fs = append(fs, &Finding{
+					Rule:     DuplicateRule,
+					Severity: Info,
+					Number:   d.Number,
+					Title:    d.Title,
+					Message:  fmt.Sprintf("possible duplicate of #%d: %s", g.Canonical.Number, strings.Join(g.Reasons, "; ")),
+				})
+				continue
+			}
+			fs = append(fs, &Finding{
+				Rule:     DuplicateRule,
+				Severity: Warning,
+				Number:   d.Number,
+				Title:    d.Title,
+				Message:  fmt.Sprintf("suspected duplicate of #%d: %s", g.Canonical.Number, strings.Join(g.Reasons, "; ")),
+				Fix: &Fix{
+					AddLabels: []string{names.Duplicate},
+					Comment:   fmt.Sprintf("Duplicate of #%d.", g.Canonical.Number),
+				},
+			})
+		}
+	}
+	return fs
+}
+
+func dedupe(list []string) []string {
+	var out []string
+	seen := map[string]bool{}
+	for _, s := range list {
+		if !seen[s] {
+			seen[s] = true
+			out = append(out, s)
+		}
+	}
+	return out
+}
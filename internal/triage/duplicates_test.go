@@ -0,0 +1,237 @@
+// Copyright 2022 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package triage
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/julieqiu/github/internal/client"
+	"golang.org/x/vuln/osv"
+)
+
+func issue(number int, module string, ids ...string) *client.Issue {
+	return &client.Issue{Number: number, ModulePath: module, Aliases: ids, Labels: map[string]bool{}}
+}
+
+func advisory(id string, cves []string, pkg, vulnerable string) *client.SecurityAdvisory {
+	sa := &client.SecurityAdvisory{
+		Identifiers: []client.Identifier{{Type: "GHSA", Value: id}},
+		Vulns:       []*client.Vuln{{Package: pkg, VulnerableVersionRange: vulnerable}},
+	}
+	for _, c := range cves {
+		sa.Identifiers = append(sa.Identifiers, client.Identifier{Type: "CVE", Value: c})
+	}
+	return sa
+}
+
+// groupString formats the groups as "canonical: duplicates" lines, with a
+// "~" after the canonical issue of an overlapping group.
+func groupString(groups []*DuplicateGroup) string {
+	var lines []string
+	for _, g := range groups {
+		var ds []string
+		for _, d := range g.Duplicates {
+			ds = append(ds, fmt.Sprint(d.Number))
+		}
+		mark := ""
+		if g.Overlapping {
+			mark = "~"
+		}
+		lines = append(lines, fmt.Sprintf("%d%s: %s", g.Canonical.Number, mark, strings.Join(ds, " ")))
+	}
+	return strings.Join(lines, "\n")
+}
+
+func TestFindDuplicates(t *testing.T) {
+	const (
+		ghsa1 = "GHSA-8r3f-844c-mc37"
+		ghsa2 = "GHSA-vp56-6g26-6827"
+		ghsa3 = "GHSA-q3j5-32m5-58c2"
+	)
+	for _, test := range []struct {
+		name       string
+		issues     []*client.Issue
+		advisories []*client.SecurityAdvisory
+		want       string
+		reason     string
+	}{
+		{
+			name:   "same CVE",
+			issues: []*client.Issue{issue(3, "a.com/m", "CVE-2022-0001"), issue(1, "b.com/m", "CVE-2022-0001"), issue(2, "a.com/m", "CVE-2022-0002")},
+			want:   "1: 3",
+			reason: "#1 and #3 mention CVE-2022-0001",
+		},
+		{
+			name:   "same GHSA in a report",
+			issues: []*client.Issue{issue(1, "a.com/m", ghsa1), {Number: 2, ModulePath: "a.com/m", OSV: &osv.Entry{ID: "GO-2022-0002", Aliases: []string{ghsa1}}}},
+			want:   "1: 2",
+		},
+		{
+			name:       "aliases in an advisory, different modules",
+			issues:     []*client.Issue{issue(1, "a.com/m", "CVE-2022-0001"), issue(2, "b.com/m", ghsa1)},
+			advisories: []*client.SecurityAdvisory{advisory(ghsa1, []string{"CVE-2022-0001"}, "c.com/m", "< 1.0.0")},
+			want:       "1: 2",
+			reason:     "#1 and #2 mention aliases in " + ghsa1,
+		},
+		{
+			name: "aliases in a report",
+			issues: []*client.Issue{
+				issue(1, "a.com/m", "CVE-2022-0001"),
+				issue(2, "", ghsa1),
+				{Number: 3, OSV: &osv.Entry{ID: "GO-2022-0003", Aliases: []string{"CVE-2022-0001", ghsa1}}},
+			},
+			want: "1: 2 3",
+		},
+		{
+			name:   "no shared IDs",
+			issues: []*client.Issue{issue(1, "a.com/m", "CVE-2022-0001"), issue(2, "a.com/m", "CVE-2022-0002")},
+		},
+		{
+			name:   "same module, overlapping advisories",
+			issues: []*client.Issue{issue(1, "a.com/m", "CVE-2022-0001"), issue(2, "a.com/m", ghsa2)},
+			advisories: []*client.SecurityAdvisory{
+				advisory(ghsa1, []string{"CVE-2022-0001"}, "a.com/m/pkg", ">= 1.0.0, < 1.2.0"),
+				advisory(ghsa2, nil, "a.com/m", "< 1.1.0"),
+			},
+			want:   "1~: 2",
+			reason: "#1 and #2 are for a.com/m, and " + ghsa1 + " and " + ghsa2 + " affect overlapping versions",
+		},
+		{
+			name:   "same module, disjoint advisories",
+			issues: []*client.Issue{issue(1, "a.com/m", "CVE-2022-0001"), issue(2, "a.com/m", ghsa2)},
+			advisories: []*client.SecurityAdvisory{
+				advisory(ghsa1, []string{"CVE-2022-0001"}, "a.com/m", ">= 1.2.0"),
+				advisory(ghsa2, nil, "a.com/m", "< 1.2.0"),
+			},
+		},
+		{
+			name:   "different modules, overlapping advisories",
+			issues: []*client.Issue{issue(1, "a.com/m", ghsa1), issue(2, "b.com/m", ghsa2)},
+			advisories: []*client.SecurityAdvisory{
+				advisory(ghsa1, nil, "a.com/m", "< 1.0.0"),
+				advisory(ghsa2, nil, "a.com/m", "< 1.0.0"),
+			},
+		},
+		{
+			name:   "no module path",
+			issues: []*client.Issue{issue(1, "", ghsa1), issue(2, "", ghsa2)},
+			advisories: []*client.SecurityAdvisory{
+				advisory(ghsa1, nil, "a.com/m", "< 1.0.0"),
+				advisory(ghsa2, nil, "a.com/m", "< 1.0.0"),
+			},
+		},
+		{
+			name: "same module, overlapping groups",
+			issues: []*client.Issue{
+				issue(1, "a.com/m", ghsa2),
+				issue(2, "a.com/m", "CVE-2022-0001"),
+				issue(3, "a.com/m", "CVE-2022-0001"),
+				issue(4, "", ghsa1),
+			},
+			advisories: []*client.SecurityAdvisory{
+				advisory(ghsa1, []string{"CVE-2022-0001"}, "a.com/m", "< 1.0.0"),
+				advisory(ghsa2, nil, "a.com/m", "< 1.0.0"),
+			},
+			want: "1~: 2\n2: 3 4",
+		},
+		{
+			name:   "chained",
+			issues: []*client.Issue{issue(1, "a.com/m", "CVE-2022-0001"), issue(2, "b.com/m", "CVE-2022-0001", ghsa3), issue(3, "c.com/m", ghsa3)},
+			want:   "1: 2 3",
+		},
+		{
+			name: "already labeled",
+			issues: []*client.Issue{
+				issue(1, "a.com/m", "CVE-2022-0001"),
+				{Number: 2, Aliases: []string{"CVE-2022-0001"}, Labels: map[string]bool{"duplicate": true}},
+			},
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			groups := FindDuplicates(test.issues, test.advisories, client.LabelNames{})
+			if got := groupString(groups); got != test.want {
+				t.Fatalf("got groups\n%s\nwant\n%s", got, test.want)
+			}
+			if test.reason != "" {
+				if rs := groups[0].Reasons; len(rs) != 1 || rs[0] != test.reason {
+					t.Errorf("reasons = %q, want [%q]", rs, test.reason)
+				}
+			}
+		})
+	}
+}
+
+func TestDuplicateCanonical(t *testing.T) {
+	dup := func(i *client.Issue) *client.Issue {
+		i.Labels["dup"] = true
+		return i
+	}
+	reported := func(i *client.Issue) *client.Issue {
+		i.HasReport = true
+		return i
+	}
+	for _, test := range []struct {
+		name   string
+		issues []*client.Issue
+		want   string
+	}{
+		{
+			name:   "oldest",
+			issues: []*client.Issue{issue(2, "", "CVE-2022-0001"), issue(1, "", "CVE-2022-0001"), issue(3, "", "CVE-2022-0001")},
+			want:   "1: 2 3",
+		},
+		{
+			name:   "reported",
+			issues: []*client.Issue{issue(1, "", "CVE-2022-0001"), reported(issue(2, "", "CVE-2022-0001"))},
+			want:   "2: 1",
+		},
+		{
+			name:   "not labeled duplicate",
+			issues: []*client.Issue{dup(issue(1, "", "CVE-2022-0001")), issue(2, "", "CVE-2022-0001"), issue(3, "", "CVE-2022-0001")},
+			want:   "2: 1 3",
+		},
+		{
+			name:   "reported and labeled duplicate",
+			issues: []*client.Issue{issue(1, "", "CVE-2022-0001"), reported(dup(issue(2, "", "CVE-2022-0001")))},
+			want:   "2: 1",
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			groups := FindDuplicates(test.issues, nil, client.LabelNames{Duplicate: "dup"})
+			if got := groupString(groups); got != test.want {
+				t.Errorf("got %q, want %q", got, test.want)
+			}
+		})
+	}
+}
+
+func TestDuplicateFindings(t *testing.T) {
+	issues := []*client.Issue{
+		issue(1, "a.com/m", "CVE-2022-0001"),
+		issue(2, "", "CVE-2022-0001"),
+		{Number: 3, Aliases: []string{"CVE-2022-0001"}, Labels: map[string]bool{"dup": true}},
+		issue(4, "a.com/m", "GHSA-vp56-6g26-6827"),
+	}
+	advisories := []*client.SecurityAdvisory{
+		advisory("GHSA-8r3f-844c-mc37", []string{"CVE-2022-0001"}, "a.com/m", "< 1.0.0"),
+		advisory("GHSA-vp56-6g26-6827", nil, "a.com/m", "< 1.0.0"),
+	}
+	names := client.LabelNames{Duplicate: "dup"}
+	fs := DuplicateFindings(FindDuplicates(issues, advisories, names), names)
+	if len(fs) != 2 {
+		t.Fatalf("got %d findings, want 2: %v", len(fs), fs)
+	}
+	f := fs[0]
+	if f.Number != 2 || f.Rule != DuplicateRule || f.Severity != Warning || f.Fix == nil ||
+		strings.Join(f.Fix.AddLabels, ",") != "dup" || f.Fix.Comment != "Duplicate of #1." {
+		t.Errorf("got %+v with fix %+v", f, f.Fix)
+	}
+	// Issues with overlapping advisories get a hint, and are not labeled.
+	if f := fs[1]; f.Number != 4 || f.Severity != Info || f.Fix != nil {
+		t.Errorf("got %+v with fix %+v, want an info finding for #4 with no fix", f, f.Fix)
+	}
+}
@@ -0,0 +1,158 @@
+// Copyright 2022 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package triage
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/julieqiu/derrors"
+)
+
+// A Fix is a change to an issue that resolves a lint finding.
+type Fix struct {
+	AddLabels    []string `json:",omitempty"`
+	RemoveLabels []string `json:",omitempty"`
+	// Comment, if not empty, is the Markdown body of a comment to add.
+	Comment string `json:",omitempty"`
+	// Close says to close the issue, with CloseReason as its state reason:
+	// "completed" or "not_planned".
+	Close       bool   `json:",omitempty"`
+	CloseReason string `json:",omitempty"`
+}
+
+func (f *Fix) String() string {
+	var parts []string
+	for _, l := range f.AddLabels {
+		parts = append(parts, "add label "+l)
+	}
+	for _, l := range f.RemoveLabels {
+		parts = append(parts, "remove label "+l)
+	}
+	if f.Comment != "" {
+		parts = append(parts, fmt.Sprintf("comment %q", f.Comment))
+	}
+	if f.Close {
+		parts = append(parts, "close as "+f.CloseReason)
+	}
+	return strings.Join(parts, ", ")
+}
+
+// A Fixer makes the changes that fixes call for.
+// It is implemented by *client.Client.
+type Fixer interface {
+	Labeler
+	AddComment(ctx context.Context, number int, body string) error
+	CloseIssue(ctx context.Context, number int, reason string) error
+}
+
+// FixableRules returns the IDs of the rules that declare a fix.
+func FixableRules() []string {
+	var ids []string
+	for _, r := range Rules {
+		if r.Fix != nil {
+			ids = append(ids, r.ID)
+		}
+	}
+	return ids
+}
+
+// PlanFixes returns the findings that have a fix and whose rule is in allow.
+// It is an error for allow to name a rule that does not exist or has no fix.
+func PlanFixes(findings []*Finding, allow []string) ([]*Finding, error) {
+	allowed := map[string]bool{}
+	for _, id := range allow {
+		r := findRule(id)
+		if r == nil {
+			return nil, fmt.Errorf("unknown lint rule %q", id)
+		}
+		if r.Fix == nil {
+			return nil, fmt.Errorf("lint rule %q has no fix", id)
+		}
+		allowed[id] = true
+	}
+	var plan []*Finding
+	for _, f := range findings {
+		if f.Fix != nil && allowed[f.Rule] {
+			plan = append(plan, f)
+		}
+	}
+	return plan, nil
+}
+
+// An AuditEntry records one change made to an issue by ApplyFixes.
+type AuditEntry struct {
+	Time   time.Time
+	Rule   string
+	Issue  int
+	Action string // "add-label", "remove-label", "comment" or "close"
+	Label  string `json:",omitempty"`
+	Reason string `json:",omitempty"`
+	// Error is the error from GitHub, if the change failed.
+	Error string `json:",omitempty"`
+}
+
+// ApplyFixes applies the fixes of the findings in order, stopping at the
+// first error. If audit is non-nil, it writes an AuditEntry to it as a line
+// of JSON for every change it attempts, including the one that failed.
+// It returns the number of findings that were fixed in full.
+func ApplyFixes(ctx context.Context, fx Fixer, findings []*Finding, audit io.Writer) (_ int, err error) {
+	defer derrors.Wrap(&err, "ApplyFixes")
+
+	var enc *json.Encoder
+	if audit != nil {
+		enc = json.NewEncoder(audit)
+	}
+	do := func(f *Finding, e AuditEntry, change func() error) error {
+		err := change()
+		if enc != nil {
+			e.Time = time.Now().UTC()
+			e.Rule = f.Rule
+			e.Issue = f.Number
+			if err != nil {
+				e.Error = err.Error()
+			}
+			if aerr := enc.Encode(e); aerr != nil && err == nil {
+				err = fmt.Errorf("writing audit log: %v", aerr)
+			}
+		}
+		return err
+	}
+	for k, f := range findings {
+		fix := f.Fix
+		if fix == nil {
+			continue
+		}
+		if len(fix.AddLabels) > 0 {
+			e := AuditEntry{Action: "add-label", Label: strings.Join(fix.AddLabels, ",")}
+			if err := do(f, e, func() error { return fx.AddLabels(ctx, f.Number, fix.AddLabels...) }); err != nil {
+				return k, err
+			}
+		}
+		for _, l := range fix.RemoveLabels {
+			l := l
+			e := AuditEntry{Action: "remove-label", Label: l}
+			if err := do(f, e, func() error { return fx.RemoveLabel(ctx, f.Number, l) }); err != nil {
+				return k, err
+			}
+		}
+		if fix.Comment != "" {
+			if err := do(f, AuditEntry{Action: "comment"}, func() error { return fx.AddComment(ctx, f.Number, fix.Comment) }); err != nil {
+				return k, err
+			}
+		}
+		if fix.Close {
+			e := AuditEntry{Action: "close", Reason: fix.CloseReason}
+			if err := do(f, e, func() error { return fx.CloseIssue(ctx, f.Number, fix.CloseReason) }); err != nil {
+				return k, err
+			}
+		}
+	}
+	return len(findings), nil
+}
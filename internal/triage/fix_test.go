@@ -0,0 +1,176 @@
+// Copyright 2022 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package triage
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// fakeFixer is a Fixer that records its calls, and fails the call whose
+// description is fail.
+type fakeFixer struct {
+	fakeLabeler
+	fail string
+}
+
+func (f *fakeFixer) AddLabels(ctx context.Context, number int, labels ...string) error {
+	f.fakeLabeler.AddLabels(ctx, number, labels...)
+	return f.result()
+}
+
+func (f *fakeFixer) RemoveLabel(ctx context.Context, number int, label string) error {
+	f.fakeLabeler.RemoveLabel(ctx, number, label)
+	return f.result()
+}
+
+func (f *fakeFixer) AddComment(_ context.Context, number int, body string) error {
+	f.calls = append(f.calls, fmt.Sprintf("comment #%d %s", number, body))
+	return f.result()
+}
+
+func (f *fakeFixer) CloseIssue(_ context.Context, number int, reason string) error {
+	f.calls = append(f.calls, fmt.Sprintf("close #%d %s", number, reason))
+	return f.result()
+}
+
+// result returns an error if the last call is the one to fail.
+func (f *fakeFixer) result() error {
+	if f.fail != "" && f.calls[len(f.calls)-1] == f.fail {
+		return errors.New("injected failure")
+	}
+	return nil
+}
+
+func TestPlanFixes(t *testing.T) {
+	findings := []*Finding{
+		{Rule: "missing-stdlib-label", Number: 1, Fix: &Fix{AddLabels: []string{"stdlib"}}},
+		{Rule: "open-with-report", Number: 2, Fix: &Fix{Close: true, CloseReason: "completed"}},
+		{Rule: "stale-needs-cve-id", Number: 3, Fix: &Fix{RemoveLabels: []string{"NeedsCVEID"}}},
+		{Rule: "missing-stdlib-label", Number: 4},
+		{Rule: "closed-without-outcome", Number: 5},
+	}
+	for _, test := range []struct {
+		allow   []string
+		want    string // issue numbers
+		wantErr string
+	}{
+		{allow: nil, want: ""},
+		{allow: []string{"missing-stdlib-label"}, want: "1"},
+		{allow: []string{"stale-needs-cve-id", "open-with-report"}, want: "2 3"},
+		{allow: FixableRules(), want: "1 2 3"},
+		{allow: []string{"closed-without-outcome"}, wantErr: "has no fix"},
+		{allow: []string{"no-such-rule"}, wantErr: "unknown lint rule"},
+	} {
+		plan, err := PlanFixes(findings, test.allow)
+		if test.wantErr != "" {
+			if err == nil || !strings.Contains(err.Error(), test.wantErr) {
+				t.Errorf("PlanFixes(%q): got error %v, want one containing %q", test.allow, err, test.wantErr)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		var got []string
+		for _, f := range plan {
+			got = append(got, fmt.Sprint(f.Number))
+		}
+		if g := strings.Join(got, " "); g != test.want {
+			t.Errorf("PlanFixes(%q) = %s, want %s", test.allow, g, test.want)
+		}
+	}
+}
+
+func TestApplyFixes(t *testing.T) {
+	findings := []*Finding{
+		{Rule: "missing-stdlib-label", Number: 1, Fix: &Fix{AddLabels: []string{"stdlib", "other"}}},
+		{Rule: "stale-needs-cve-id", Number: 2, Fix: &Fix{RemoveLabels: []string{"NeedsCVEID"}}},
+		{Rule: DuplicateRule, Number: 3, Fix: &Fix{AddLabels: []string{"duplicate"}, Comment: "Duplicate of #1."}},
+		{Rule: "open-with-report", Number: 4, Fix: &Fix{Close: true, CloseReason: "completed"}},
+	}
+	ctx := context.Background()
+
+	// audit decodes the audit log into "rule issue action label reason error"
+	// lines, checking that every entry has a time.
+	audit := func(buf *bytes.Buffer) []string {
+		t.Helper()
+		var lines []string
+		dec := json.NewDecoder(buf)
+		for dec.More() {
+			var e AuditEntry
+			if err := dec.Decode(&e); err != nil {
+				t.Fatal(err)
+			}
+			if e.Time.IsZero() {
+				t.Errorf("audit entry %+v has no time", e)
+			}
+			lines = append(lines, strings.TrimSpace(fmt.Sprintf("%s #%d %s %s %s %s", e.Rule, e.Issue, e.Action, e.Label, e.Reason, e.Error)))
+		}
+		return lines
+	}
+
+	var f fakeFixer
+	var buf bytes.Buffer
+	n, err := ApplyFixes(ctx, &f, findings, &buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != len(findings) {
+		t.Errorf("fixed %d of %d findings", n, len(findings))
+	}
+	wantCalls := []string{
+		"add #1 stdlib,other",
+		"remove #2 NeedsCVEID",
+		"add #3 duplicate",
+		"comment #3 Duplicate of #1.",
+		"close #4 completed",
+	}
+	if got, want := strings.Join(f.calls, "\n"), strings.Join(wantCalls, "\n"); got != want {
+		t.Errorf("got calls\n%s\nwant\n%s", got, want)
+	}
+	wantAudit := []string{
+		"missing-stdlib-label #1 add-label stdlib,other",
+		"stale-needs-cve-id #2 remove-label NeedsCVEID",
+		"duplicate #3 add-label duplicate",
+		"duplicate #3 comment",
+		"open-with-report #4 close  completed",
+	}
+	if got, want := strings.Join(audit(&buf), "\n"), strings.Join(wantAudit, "\n"); got != want {
+		t.Errorf("got audit log\n%s\nwant\n%s", got, want)
+	}
+
+	// A failed change stops the run, and is logged with its error.
+	f = fakeFixer{fail: "comment #3 Duplicate of #1."}
+	buf.Reset()
+	n, err = ApplyFixes(ctx, &f, findings, &buf)
+	if err == nil || !strings.Contains(err.Error(), "injected failure") {
+		t.Fatalf("got error %v, want the injected failure", err)
+	}
+	if n != 2 {
+		t.Errorf("fixed %d findings before the failure, want 2", n)
+	}
+	if got := len(f.calls); got != 4 {
+		t.Errorf("made %d calls, want 4: %q", got, f.calls)
+	}
+	lines := audit(&buf)
+	if want := "duplicate #3 comment   injected failure"; len(lines) != 4 || lines[3] != want {
+		t.Errorf("got audit log %q, want 4 lines ending in %q", lines, want)
+	}
+
+	// The audit log is optional.
+	f = fakeFixer{}
+	if _, err := ApplyFixes(ctx, &f, findings, nil); err != nil {
+		t.Fatal(err)
+	}
+	if got := len(f.calls); got != len(wantCalls) {
+		t.Errorf("without an audit log, made %d calls, want %d", got, len(wantCalls))
+	}
+}
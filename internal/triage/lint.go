@@ -0,0 +1,259 @@
+// Copyright 2022 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package triage
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/julieqiu/github/internal/client"
+)
+
+// A Severity says how serious a lint finding is.
+type Severity int
+
+const (
+	Info Severity = iota
+	Warning
+	Error
+)
+
+var severityNames = []string{"info", "warning", "error"}
+
+func (s Severity) String() string {
+	if s < 0 || int(s) >= len(severityNames) {
+		return fmt.Sprintf("Severity(%d)", int(s))
+	}
+	return severityNames[s]
+}
+
+// MarshalText implements encoding.TextMarshaler, so that severities appear
+// by name in JSON.
+func (s Severity) MarshalText() ([]byte, error) {
+	return []byte(s.String()), nil
+}
+
+// ParseSeverity returns the Severity with the given name.
+func ParseSeverity(name string) (Severity, error) {
+	for k, n := range severityNames {
+		if n == name {
+			return Severity(k), nil
+		}
+	}
+	return 0, fmt.Errorf("unknown severity %q (want one of %s)", name, strings.Join(severityNames, ", "))
+}
+
+// A Rule checks issues for one kind of inconsistency.
+type Rule struct {
+	ID       string
+	Severity Severity
+	// Description says what the rule checks. It calls labels by their
+	// default names.
+	Description string
+	// Check returns a message describing the problem with the issue, or ""
+	// if there is none. The issue's HasReport and OSV fields must be set,
+	// and its labels have the given names.
+	Check func(i *client.Issue, names client.LabelNames) string `json:"-"`
+	// Fix, if non-nil, returns the change that resolves a problem found by
+	// Check. See ApplyFixes.
+	Fix func(i *client.Issue, names client.LabelNames) *Fix `json:"-"`
+}
+
+// A Finding is a problem found by a rule.
+type Finding struct {
+	Rule     string
+	Severity Severity
+	Number   int
+	Title    string
+	Message  string
+	// Fix is the change that resolves the finding, if its rule has one.
+	Fix *Fix `json:",omitempty"`
+}
+
+func (f *Finding) String() string {
+	return fmt.Sprintf("#%d: %s: %s [%s]", f.Number, f.Severity, f.Message, f.Rule)
+}
+
+// outcomeLabels returns the labels that record why an issue was closed.
+func outcomeLabels(names client.LabelNames) []string {
+	return []string{names.NotGoVuln, names.NeedsReport, names.Duplicate}
+}
+
+// contradictoryLabels returns the pairs of labels that an issue must not have
+// together.
+func contradictoryLabels(names client.LabelNames) [][2]string {
+	return [][2]string{
+		{names.NotGoVuln, names.NeedsReport},
+		{names.NotGoVuln, names.Duplicate},
+		{names.NeedsReport, names.Duplicate},
+	}
+}
+
+// Rules are the lint rules, in the order they are run.
+var Rules = []*Rule{
+	{
+		ID:          "missing-stdlib-label",
+		Severity:    Warning,
+		Description: "standard library issues must have the stdlib label",
+		Check: func(i *client.Issue, names client.LabelNames) string {
+			if i.IsStdLib && !i.Labels[names.StdLib] {
+				return fmt.Sprintf("issue for %s has no %s label", i.ModulePath, names.StdLib)
+			}
+			return ""
+		},
+		Fix: func(i *client.Issue, names client.LabelNames) *Fix {
+			return &Fix{AddLabels: []string{names.StdLib}}
+		},
+	},
+	{
+		ID:          "closed-without-outcome",
+		Severity:    Error,
+		Description: "closed issues must say why, with a NotGoVuln, NeedsReport or duplicate label",
+		Check: func(i *client.Issue, names client.LabelNames) string {
+			if i.Open || i.HasReport {
+				return ""
+			}
+			for _, l := range outcomeLabels(names) {
+				if i.Labels[l] {
+					return ""
+				}
+			}
+			return "closed issue has no " + strings.Join(outcomeLabels(names), ", ") + " label"
+		},
+	},
+	{
+		ID:          "needs-report-unpublished",
+		Severity:    Warning,
+		Description: "issues labeled NeedsReport should have a published report",
+		Check: func(i *client.Issue, names client.LabelNames) string {
+			if i.Labels[names.NeedsReport] && !i.HasReport {
+				return "labeled " + names.NeedsReport + " but no report is published"
+			}
+			return ""
+		},
+	},
+	{
+		ID:          "open-with-report",
+		Severity:    Error,
+		Description: "issues whose report is published must be closed",
+		Check: func(i *client.Issue, _ client.LabelNames) string {
+			if i.Open && i.HasReport {
+				return fmt.Sprintf("report %s is published but the issue is open", i.OSV.ID)
+			}
+			return ""
+		},
+		Fix: func(i *client.Issue, _ client.LabelNames) *Fix {
+			return &Fix{Close: true, CloseReason: "completed"}
+		},
+	},
+	{
+		ID:          "contradictory-labels",
+		Severity:    Error,
+		Description: "issues must not have labels that contradict each other",
+		Check: func(i *client.Issue, names client.LabelNames) string {
+			var pairs []string
+			for _, p := range contradictoryLabels(names) {
+				if i.Labels[p[0]] && i.Labels[p[1]] {
+					pairs = append(pairs, p[0]+" and "+p[1])
+				}
+			}
+			if len(pairs) == 0 {
+				return ""
+			}
+			return "labeled both " + strings.Join(pairs, ", and both ")
+		},
+	},
+	{
+		ID:          "stale-needs-cve-id",
+		Severity:    Warning,
+		Description: "issues labeled NeedsCVEID must not already have a CVE",
+		Check: func(i *client.Issue, names client.LabelNames) string {
+			if !i.Labels[names.NeedsCVEID] {
+				return ""
+			}
+			if cve := issueCVE(i); cve != "" {
+				return "labeled " + names.NeedsCVEID + " but has " + cve
+			}
+			return ""
+		},
+		Fix: func(i *client.Issue, names client.LabelNames) *Fix {
+			return &Fix{RemoveLabels: []string{names.NeedsCVEID}}
+		},
+	},
+}
+
+// issueCVE returns a CVE for the issue, from its title and body or from its
+// report, or "" if it has none.
+func issueCVE(i *client.Issue) string {
+	if i.CVE != "" {
+		return i.CVE
+	}
+	if i.OSV != nil {
+		for _, a := range i.OSV.Aliases {
+			if strings.HasPrefix(a, "CVE-") {
+				return a
+			}
+		}
+	}
+	return ""
+}
+
+// SelectRules returns the rules with the given IDs, or all rules if ids is
+// empty.
+func SelectRules(ids []string) ([]*Rule, error) {
+	if len(ids) == 0 {
+		return Rules, nil
+	}
+	var rules []*Rule
+	for _, id := range ids {
+		r := findRule(id)
+		if r == nil {
+			return nil, fmt.Errorf("unknown lint rule %q", id)
+		}
+		rules = append(rules, r)
+	}
+	return rules, nil
+}
+
+func findRule(id string) *Rule {
+	for _, r := range Rules {
+		if r.ID == id {
+			return r
+		}
+	}
+	return nil
+}
+
+// Lint runs the rules over the issues, whose labels have the given names, and
+// returns the findings, most severe first and then by issue number.
+func Lint(issues []*client.Issue, rules []*Rule, names client.LabelNames) []*Finding {
+	names = names.WithDefaults()
+	var fs []*Finding
+	for _, i := range issues {
+		for _, r := range rules {
+			if msg := r.Check(i, names); msg != "" {
+				f := &Finding{
+					Rule:     r.ID,
+					Severity: r.Severity,
+					Number:   i.Number,
+					Title:    i.Title,
+					Message:  msg,
+				}
+				if r.Fix != nil {
+					f.Fix = r.Fix(i, names)
+				}
+				fs = append(fs, f)
+			}
+		}
+	}
+	sort.SliceStable(fs, func(i, j int) bool {
+		if fs[i].Severity != fs[j].Severity {
+			return fs[i].Severity > fs[j].Severity
+		}
+		return fs[i].Number < fs[j].Number
+	})
+	return fs
+}
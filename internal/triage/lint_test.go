@@ -0,0 +1,77 @@
+// Copyright 2022 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package triage
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/julieqiu/github/internal/client"
+)
+
+// renamed are label names that differ from the defaults.
+var renamed = client.LabelNames{
+	NotGoVuln:   "not-go",
+	NeedsReport: "needs-report",
+	Duplicate:   "dup",
+	StdLib:      "std",
+	NeedsCVEID:  "needs-cve",
+}
+
+func TestLintLabelNames(t *testing.T) {
+	issues := []*client.Issue{
+		{Number: 1, ModulePath: "net/http", IsStdLib: true, Open: true, Labels: map[string]bool{"stdlib": true}},
+		{Number: 2, Labels: map[string]bool{"NotGoVuln": true}},
+		{Number: 3, Labels: map[string]bool{"not-go": true, "dup": true}},
+		{Number: 4, Labels: map[string]bool{"needs-report": true}, Open: true},
+		{Number: 5, CVE: "CVE-2022-0001", Labels: map[string]bool{"needs-cve": true, "not-go": true}},
+	}
+	var got []string
+	for _, f := range Lint(issues, Rules, renamed) {
+		s := f.String()
+		if f.Fix != nil {
+			s += " fix: " + f.Fix.String()
+		}
+		got = append(got, s)
+	}
+	want := []string{
+		"#2: error: closed issue has no not-go, needs-report, dup label [closed-without-outcome]",
+		"#3: error: labeled both not-go and dup [contradictory-labels]",
+		"#1: warning: issue for net/http has no std label [missing-stdlib-label] fix: add label std",
+		"#4: warning: labeled needs-report but no report is published [needs-report-unpublished]",
+		"#5: warning: labeled needs-cve but has CVE-2022-0001 [stale-needs-cve-id] fix: remove label needs-cve",
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("got\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+}
+
+func TestQueryStatusLabelNames(t *testing.T) {
+	issues := []*client.Issue{
+		{Number: 1, Open: true, Labels: map[string]bool{"needs-report": true}},
+		{Number: 2, Open: true, Labels: map[string]bool{"NeedsReport": true}},
+	}
+	for _, test := range []struct {
+		names client.LabelNames
+		want  int
+	}{
+		{renamed, 1},
+		{client.DefaultLabelNames, 2},
+	} {
+		q, err := ParseQuery("status:needs-report", test.names)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var got []int
+		for _, i := range issues {
+			if q.Match(i) {
+				got = append(got, i.Number)
+			}
+		}
+		if len(got) != 1 || got[0] != test.want {
+			t.Errorf("with %+v: matched %v, want [%d]", test.names, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,107 @@
+// Copyright 2022 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package triage helps keep the vulndb issues consistently labeled.
+package triage
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/julieqiu/github/internal/client"
+)
+
+// A Query selects issues. It is a list of space-separated terms, all of
+// which must match:
+//
+//	is:open, is:closed          the issue's state
+//	is:stdlib, is:thirdparty    whether the issue is for the standard library
+//	is:reported                 the issue has a vulndb report
+//	status:NAME                 the issue's lifecycle status; see client.Status
+//	label:NAME                  the issue has the label
+//	module:PREFIX               the module path starts with PREFIX
+//	number:N                    the issue number is N
+//
+// A term prefixed with "-" matches the issues that the term does not. For
+// example, "is:stdlib -label:stdlib" selects the standard library issues
+// that are missing the stdlib label.
+type Query struct {
+	text  string
+	terms []queryTerm
+}
+
+type queryTerm struct {
+	negate bool
+	match  func(*client.Issue) bool
+}
+
+// ParseQuery parses a Query. The status: terms derive the statuses of issues
+// from labels with the given names.
+func ParseQuery(s string, names client.LabelNames) (*Query, error) {
+	names = names.WithDefaults()
+	q := &Query{text: s}
+	for _, f := range strings.Fields(s) {
+		t := queryTerm{}
+		if strings.HasPrefix(f, "-") {
+			t.negate = true
+			f = f[1:]
+		}
+		key, val, ok := strings.Cut(f, ":")
+		if !ok || val == "" {
+			return nil, fmt.Errorf("query term %q: want KEY:VALUE", f)
+		}
+		switch key {
+		case "is":
+			switch val {
+			case "open":
+				t.match = func(i *client.Issue) bool { return i.Open }
+			case "closed":
+				t.match = func(i *client.Issue) bool { return !i.Open }
+			case "stdlib":
+				t.match = func(i *client.Issue) bool { return i.IsStdLib }
+			case "thirdparty":
+				t.match = func(i *client.Issue) bool { return !i.IsStdLib }
+			case "reported":
+				t.match = func(i *client.Issue) bool { return i.HasReport }
+			default:
+				return nil, fmt.Errorf("query term %q: unknown value %q", f, val)
+			}
+		case "status":
+			st, err := client.ParseStatus(val)
+			if err != nil {
+				return nil, fmt.Errorf("query term %q: %v", f, err)
+			}
+			t.match = func(i *client.Issue) bool { return i.StatusUsing(names) == st }
+		case "label":
+			t.match = func(i *client.Issue) bool { return i.Labels[val] }
+		case "module":
+			t.match = func(i *client.Issue) bool { return strings.HasPrefix(i.ModulePath, val) }
+		case "number":
+			n, err := strconv.Atoi(val)
+			if err != nil {
+				return nil, fmt.Errorf("query term %q: %v", f, err)
+			}
+			t.match = func(i *client.Issue) bool { return i.Number == n }
+		default:
+			return nil, fmt.Errorf("query term %q: unknown key %q", f, key)
+		}
+		q.terms = append(q.terms, t)
+	}
+	return q, nil
+}
+
+// Match reports whether the issue matches every term of the query.
+func (q *Query) Match(i *client.Issue) bool {
+	for _, t := range q.terms {
+		if t.match(i) == t.negate {
+			return false
+		}
+	}
+	return true
+}
+
+func (q *Query) String() string {
+	return q.text
+}
@@ -0,0 +1,146 @@
+// Copyright 2022 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package triage
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/julieqiu/derrors"
+	"github.com/julieqiu/github/internal/client"
+)
+
+// A LabelEdit describes how to change the labels of an issue.
+type LabelEdit struct {
+	Add    []string
+	Remove []string
+	// Set, if non-nil, is the complete list of labels the issue should have.
+	// Add and Remove must be empty if Set is used.
+	Set []string
+}
+
+// A LabelChange is a change to the labels of one issue.
+type LabelChange struct {
+	Number int
+	Title  string
+	Add    []string
+	Remove []string
+	// Set is the complete list of labels after the change, if the change
+	// was planned from a LabelEdit that sets the labels.
+	Set []string
+}
+
+// String formats the change as a diff, with a line per label.
+func (c *LabelChange) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "#%d %s\n", c.Number, c.Title)
+	for _, l := range c.Remove {
+		fmt.Fprintf(&b, "  - %s\n", l)
+	}
+	for _, l := range c.Add {
+		fmt.Fprintf(&b, "  + %s\n", l)
+	}
+	return b.String()
+}
+
+// PlanRelabel returns the changes that apply edit to the issues that match q,
+// in increasing order of issue number. Issues whose labels would not change
+// are omitted.
+func PlanRelabel(issues []*client.Issue, q *Query, edit LabelEdit) ([]*LabelChange, error) {
+	if edit.Set != nil && (len(edit.Add) > 0 || len(edit.Remove) > 0) {
+		return nil, fmt.Errorf("cannot both set labels and add or remove them")
+	}
+	var changes []*LabelChange
+	for _, i := range issues {
+		if !q.Match(i) {
+			continue
+		}
+		c := &LabelChange{Number: i.Number, Title: i.Title}
+		add, remove := edit.Add, edit.Remove
+		if edit.Set != nil {
+			want := map[string]bool{}
+			for _, l := range edit.Set {
+				want[l] = true
+			}
+			add = edit.Set
+			remove = nil
+			c.Set = []string{}
+			for _, l := range edit.Set {
+				if !contains(c.Set, l) {
+					c.Set = append(c.Set, l)
+				}
+			}
+			sort.Strings(c.Set)
+			for l := range i.Labels {
+				if !want[l] {
+					remove = append(remove, l)
+				}
+			}
+		}
+		for _, l := range add {
+			if !i.Labels[l] && !contains(c.Add, l) {
+				c.Add = append(c.Add, l)
+			}
+		}
+		for _, l := range remove {
+			if i.Labels[l] && !contains(c.Remove, l) {
+				c.Remove = append(c.Remove, l)
+			}
+		}
+		if len(c.Add) == 0 && len(c.Remove) == 0 {
+			continue
+		}
+		sort.Strings(c.Add)
+		sort.Strings(c.Remove)
+		changes = append(changes, c)
+	}
+	sort.Slice(changes, func(i, j int) bool { return changes[i].Number < changes[j].Number })
+	return changes, nil
+}
+
+// A Labeler changes the labels of issues.
+// It is implemented by *client.Client.
+type Labeler interface {
+	AddLabels(ctx context.Context, number int, labels ...string) error
+	RemoveLabel(ctx context.Context, number int, label string) error
+	ReplaceLabels(ctx context.Context, number int, labels []string) error
+}
+
+// ApplyLabelChanges makes the changes in order, stopping at the first error.
+// It returns the number of changes that were made in full. A change with Set
+// replaces the labels in one request; others add and remove them.
+func ApplyLabelChanges(ctx context.Context, l Labeler, changes []*LabelChange) (_ int, err error) {
+	defer derrors.Wrap(&err, "ApplyLabelChanges")
+	for k, c := range changes {
+		if c.Set != nil {
+			if err := l.ReplaceLabels(ctx, c.Number, c.Set); err != nil {
+				return k, err
+			}
+			continue
+		}
+		if len(c.Add) > 0 {
+			if err := l.AddLabels(ctx, c.Number, c.Add...); err != nil {
+				return k, err
+			}
+		}
+		for _, lab := range c.Remove {
+			if err := l.RemoveLabel(ctx, c.Number, lab); err != nil {
+				return k, err
+			}
+		}
+	}
+	return len(changes), nil
+}
+
+func contains(list []string, s string) bool {
+	for _, x := range list {
+		if x == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,102 @@
+// Copyright 2022 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package triage
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/julieqiu/github/internal/client"
+)
+
+// fakeLabeler is a Labeler that records its calls.
+type fakeLabeler struct {
+	calls []string
+}
+
+func (f *fakeLabeler) AddLabels(_ context.Context, number int, labels ...string) error {
+	f.calls = append(f.calls, fmt.Sprintf("add #%d %s", number, strings.Join(labels, ",")))
+	return nil
+}
+
+func (f *fakeLabeler) RemoveLabel(_ context.Context, number int, label string) error {
+	f.calls = append(f.calls, fmt.Sprintf("remove #%d %s", number, label))
+	return nil
+}
+
+func (f *fakeLabeler) ReplaceLabels(_ context.Context, number int, labels []string) error {
+	f.calls = append(f.calls, fmt.Sprintf("replace #%d %s", number, strings.Join(labels, ",")))
+	return nil
+}
+
+func labeled(number int, labels ...string) *client.Issue {
+	i := &client.Issue{Number: number, Labels: map[string]bool{}}
+	for _, l := range labels {
+		i.Labels[l] = true
+	}
+	return i
+}
+
+func TestRelabel(t *testing.T) {
+	issues := []*client.Issue{
+		labeled(2, "a", "b"),
+		labeled(1, "a"),
+		labeled(3, "c"),
+	}
+	q, err := ParseQuery("-number:3", client.DefaultLabelNames)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, test := range []struct {
+		name string
+		edit LabelEdit
+		want []string
+	}{
+		{
+			name: "add and remove",
+			edit: LabelEdit{Add: []string{"c"}, Remove: []string{"b"}},
+			want: []string{"add #1 c", "add #2 c", "remove #2 b"},
+		},
+		{
+			name: "set",
+			edit: LabelEdit{Set: []string{"c", "a", "c"}},
+			want: []string{"replace #1 a,c", "replace #2 a,c"},
+		},
+		{
+			name: "set none",
+			edit: LabelEdit{Set: []string{}},
+			want: []string{"replace #1 ", "replace #2 "},
+		},
+		{
+			name: "set unchanged",
+			edit: LabelEdit{Set: []string{"a"}},
+			want: []string{"replace #2 a"},
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			changes, err := PlanRelabel(issues, q, test.edit)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var f fakeLabeler
+			n, err := ApplyLabelChanges(context.Background(), &f, changes)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if n != len(changes) {
+				t.Errorf("made %d of %d changes", n, len(changes))
+			}
+			if got, want := strings.Join(f.calls, "; "), strings.Join(test.want, "; "); got != want {
+				t.Errorf("got calls\n%s\nwant\n%s", got, want)
+			}
+		})
+	}
+
+	if _, err := PlanRelabel(issues, q, LabelEdit{Add: []string{"a"}, Set: []string{"b"}}); err == nil {
+		t.Error("PlanRelabel accepted both Add and Set")
+	}
+}
@@ -0,0 +1,67 @@
+// Copyright 2022 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package worker
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/julieqiu/github/internal/client"
+	"github.com/julieqiu/github/internal/report"
+)
+
+// issueAdvisory returns the advisory for the first GHSA in the aliases of the
+// issue that the snapshot has, or else an advisory for the first CVE in them
+// that has one, or nil if there is none.
+func (snap *snapshot) issueAdvisory(i *client.Issue) *client.SecurityAdvisory {
+	for _, a := range i.Aliases {
+		if sa := snap.ghsaByID[a]; sa != nil {
+			return sa
+		}
+	}
+	for _, a := range i.Aliases {
+		if sa := snap.ghsaByCVE[a]; sa != nil {
+			return sa
+		}
+	}
+	return nil
+}
+
+// draftReport serves /draft/N.yaml, a draft vulndb report for issue N made
+// from its GHSA, or from a GHSA for its CVE. See report.FromGHSA.
+func (s *Server) draftReport(w http.ResponseWriter, r *http.Request) error {
+	name := strings.TrimPrefix(r.URL.Path, "/draft/")
+	n, err := strconv.Atoi(strings.TrimSuffix(name, ".yaml"))
+	if err != nil || !strings.HasSuffix(name, ".yaml") {
+		return &serverError{status: http.StatusNotFound, err: fmt.Errorf("bad draft path %q", r.URL.Path)}
+	}
+	snap, _ := s.currentSnapshot()
+	var issue *client.Issue
+	for _, i := range snap.issues {
+		if i.Number == n {
+			issue = i
+			break
+		}
+	}
+	if issue == nil {
+		return &serverError{status: http.StatusNotFound, err: fmt.Errorf("no issue #%d", n)}
+	}
+	sa := snap.issueAdvisory(issue)
+	if sa == nil {
+		return &serverError{status: http.StatusNotFound, err: fmt.Errorf("no known GHSA for issue #%d", n)}
+	}
+	var buf bytes.Buffer
+	if err := report.FromGHSA(sa, issue.ModulePath).WriteYAML(&buf); err != nil {
+		return err
+	}
+	// vulndb names reports after the year they are added and the issue.
+	w.Header().Set("Content-Type", "text/yaml; charset=utf-8")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=GO-%d-%04d.yaml", s.now().Year(), n))
+	_, err = w.Write(buf.Bytes())
+	return err
+}
@@ -0,0 +1,95 @@
+// Copyright 2022 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package worker
+
+import (
+	"net/url"
+	"strings"
+
+	"github.com/julieqiu/github/internal/client"
+)
+
+// An issueFilter selects the issues shown on the dashboard. Empty fields
+// match every issue.
+type issueFilter struct {
+	State     string // "open" or "closed"
+	Status    client.Status
+	Assignee  string // login, or "none" for unassigned issues
+	Author    string
+	Milestone string // title, or "none" for issues without one
+	Label     string
+}
+
+// parseIssueFilter reads an issueFilter from the query parameters state,
+// status, assignee, author, milestone and label.
+func parseIssueFilter(q url.Values) issueFilter {
+	get := func(k string) string { return strings.TrimSpace(q.Get(k)) }
+	return issueFilter{
+		State:     strings.ToLower(get("state")),
+		Status:    client.Status(strings.ToLower(get("status"))),
+		Assignee:  get("assignee"),
+		Author:    get("author"),
+		Milestone: get("milestone"),
+		Label:     get("label"),
+	}
+}
+
+// IsZero reports whether f matches every issue.
+func (f issueFilter) IsZero() bool {
+	return f == issueFilter{}
+}
+
+// match reports whether the issue passes the filter, deriving its status with
+// the given label names. Logins and milestone titles are compared
+// case-insensitively.
+func (f issueFilter) match(i *client.Issue, names client.LabelNames) bool {
+	switch f.State {
+	case "open":
+		if !i.Open {
+			return false
+		}
+	case "closed":
+		if i.Open {
+			return false
+		}
+	}
+	if f.Status != "" && i.StatusUsing(names) != f.Status {
+		return false
+	}
+	if f.Assignee != "" {
+		if strings.EqualFold(f.Assignee, "none") {
+			if len(i.Assignees) > 0 {
+				return false
+			}
+		} else if !containsFold(i.Assignees, f.Assignee) {
+			return false
+		}
+	}
+	if f.Author != "" && !strings.EqualFold(i.Author, f.Author) {
+		return false
+	}
+	if f.Milestone != "" {
+		if strings.EqualFold(f.Milestone, "none") {
+			if i.Milestone != "" {
+				return false
+			}
+		} else if !strings.EqualFold(i.Milestone, f.Milestone) {
+			return false
+		}
+	}
+	if f.Label != "" && !i.Labels[f.Label] {
+		return false
+	}
+	return true
+}
+
+func containsFold(list []string, s string) bool {
+	for _, x := range list {
+		if strings.EqualFold(x, s) {
+			return true
+		}
+	}
+	return false
+}
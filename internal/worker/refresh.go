@@ -0,0 +1,255 @@
+// Copyright 2022 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package worker
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	log "github.com/julieqiu/dlog"
+	"github.com/julieqiu/github/internal/client"
+	"github.com/julieqiu/github/internal/colly"
+	"github.com/julieqiu/github/internal/reconcile"
+	"github.com/julieqiu/github/internal/triage"
+	"golang.org/x/vuln/osv"
+)
+
+// A SourceStatus describes the outcome of refreshing one source of data.
+type SourceStatus struct {
+	Name string
+	// LastRefresh is the time of the last successful refresh.
+	LastRefresh time.Time
+	// Err is the error from the most recent refresh, or nil if it succeeded.
+	Err error
+}
+
+// Names of the sources of dashboard data, in display order.
+var sourceNames = []string{"issues", "GHSAs", "vulndb", "release notes"}
+
+// A snapshot is the data rendered by the dashboard as of a refresh.
+// It must not be modified once it has been installed on the Server.
+type snapshot struct {
+	// The data as returned by the sources.
+	rawIssues    []*client.Issue
+	malformed    []*client.MalformedIssue
+	ghsas        []*client.SecurityAdvisory
+	reports      []*osv.Entry
+	releaseNotes []*colly.ReleaseNote
+
+	// ghsaByID maps the GHSA IDs of ghsas to the advisories.
+	ghsaByID map[string]*client.SecurityAdvisory
+	// ghsaByCVE maps the CVE IDs of ghsas to the advisories. A CVE with
+	// more than one advisory maps to the one with the lowest GHSA ID.
+	ghsaByCVE map[string]*client.SecurityAdvisory
+	// issues are copies of rawIssues with their vulndb reports attached.
+	issues       []*client.Issue
+	numDBReports int
+	// mismatches are the disagreements between reports and the GHSAs in
+	// ghsas; see reconcile.CheckReports.
+	mismatches []*reconcile.Mismatch
+	// duplicates are the groups of issues that seem to be duplicates of
+	// each other and are not all labeled as such.
+	duplicates []*triage.DuplicateGroup
+	// badTransitions are the most recent changes of issue status, seen
+	// between refreshes, that client.CheckTransition does not allow. They
+	// are newest first.
+	badTransitions []*statusChange
+}
+
+// A statusChange is a change in the status of an issue between two
+// refreshes.
+type statusChange struct {
+	Issue *client.Issue
+	From  client.Status
+	To    client.Status
+	// Seen is the time of the refresh that saw the change.
+	Seen time.Time
+	Err  error
+}
+
+// maxBadTransitions is the number of invalid status changes kept for the
+// dashboard.
+const maxBadTransitions = 100
+
+// refreshLoop refreshes the dashboard data immediately and then every
+// s.refreshInterval, until ctx is done.
+func (s *Server) refreshLoop(ctx context.Context) {
+	s.refresh(ctx)
+	ticker := time.NewTicker(s.refreshInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			s.refresh(ctx)
+		}
+	}
+}
+
+// refresh fetches from every source and installs a new snapshot. Data from a
+// source that fails is carried over from the previous snapshot.
+// If a refresh is already in progress, refresh returns immediately.
+func (s *Server) refresh(ctx context.Context) {
+	if !s.refreshMu.TryLock() {
+		log.Infof(ctx, "refresh already in progress")
+		return
+	}
+	defer s.refreshMu.Unlock()
+
+	log.Infof(ctx, "refreshing")
+	s.mu.Lock()
+	snap := *s.snap
+	s.mu.Unlock()
+
+	var (
+		wg           sync.WaitGroup
+		errs         = make([]error, len(sourceNames))
+		issues       []*client.Issue
+		malformed    []*client.MalformedIssue
+		ghsas        []*client.SecurityAdvisory
+		reports      []*osv.Entry
+		releaseNotes []*colly.ReleaseNote
+	)
+	fetchers := []func() error{
+		func() (err error) {
+			issues, malformed, err = s.sources.Issues.ListIssues(ctx)
+			return err
+		},
+		func() (err error) {
+			ghsas, err = s.sources.Advisories.ListGHSAs(ctx)
+			return err
+		},
+		func() (err error) {
+			reports, err = s.sources.Reports.ListReports(ctx)
+			return err
+		},
+		func() (err error) {
+			releaseNotes, err = s.sources.ReleaseNotes.ReleaseNotes()
+			return err
+		},
+	}
+	for k, f := range fetchers {
+		k, f := k, f
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs[k] = f()
+		}()
+	}
+	wg.Wait()
+
+	if errs[0] == nil {
+		snap.rawIssues = issues
+		snap.malformed = malformed
+	}
+	if errs[1] == nil {
+		snap.ghsas = ghsas
+		snap.ghsaByID = map[string]*client.SecurityAdvisory{}
+		snap.ghsaByCVE = map[string]*client.SecurityAdvisory{}
+		for _, sa := range ghsas {
+			snap.ghsaByID[sa.PrettyID()] = sa
+			for _, id := range sa.Identifiers {
+				if id.Type != "CVE" {
+					continue
+				}
+				if old := snap.ghsaByCVE[id.Value]; old == nil || sa.PrettyID() < old.PrettyID() {
+					snap.ghsaByCVE[id.Value] = sa
+				}
+			}
+		}
+	}
+	if errs[2] == nil {
+		snap.reports = reports
+	}
+	if errs[3] == nil {
+		snap.releaseNotes = releaseNotes
+	}
+	prev := snap.issues
+	snap.attachReports(ctx)
+	now := s.now()
+	snap.checkTransitions(ctx, prev, s.rules.Labels, now)
+	// With no fetcher, CheckReports makes no requests and cannot fail.
+	snap.mismatches, _ = reconcile.CheckReports(ctx, nil, snap.issues, snap.ghsas)
+	snap.duplicates = triage.FindDuplicates(snap.issues, snap.ghsas, s.rules.Labels)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for k, st := range s.statuses {
+		st.Err = errs[k]
+		if errs[k] != nil {
+			log.Errorf(ctx, "refreshing %s: %v", st.Name, errs[k])
+			continue
+		}
+		st.LastRefresh = now
+	}
+	s.snap = &snap
+	log.Infof(ctx, "refreshed %d issues", len(snap.issues))
+}
+
+// attachReports sets snap.issues to copies of snap.rawIssues, with each
+// vulndb report attached to the issue it was created for.
+func (snap *snapshot) attachReports(ctx context.Context) {
+	dbReports := map[int]*osv.Entry{}
+	for _, e := range snap.reports {
+		n, err := client.ReportIssueNumber(e.ID)
+		if err != nil {
+			log.Warningf(ctx, "%v", err)
+			continue
+		}
+		dbReports[n] = e
+	}
+
+	snap.issues = nil
+	for _, raw := range snap.rawIssues {
+		i := *raw
+		snap.issues = append(snap.issues, &i)
+		i.SetReport(dbReports[i.Number])
+	}
+	snap.numDBReports = len(dbReports)
+}
+
+// checkTransitions compares the status of each issue with its status in prev,
+// and records the changes that are not allowed. Statuses are derived with
+// the given label names.
+func (snap *snapshot) checkTransitions(ctx context.Context, prev []*client.Issue, names client.LabelNames, now time.Time) {
+	before := map[int]client.Status{}
+	for _, i := range prev {
+		before[i.Number] = i.StatusUsing(names)
+	}
+	var bad []*statusChange
+	for _, i := range snap.issues {
+		from, ok := before[i.Number]
+		if !ok {
+			continue
+		}
+		to := i.StatusUsing(names)
+		if err := client.CheckTransition(from, to); err != nil {
+			log.Warningf(ctx, "issue %d: %v", i.Number, err)
+			bad = append(bad, &statusChange{Issue: i, From: from, To: to, Seen: now, Err: err})
+		}
+	}
+	sort.Slice(bad, func(i, j int) bool { return bad[i].Issue.Number < bad[j].Issue.Number })
+	bad = append(bad, snap.badTransitions...)
+	if len(bad) > maxBadTransitions {
+		bad = bad[:maxBadTransitions]
+	}
+	snap.badTransitions = bad
+}
+
+// currentSnapshot returns the most recent snapshot and a copy of the status
+// of each source.
+func (s *Server) currentSnapshot() (*snapshot, []*SourceStatus) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var sources []*SourceStatus
+	for _, st := range s.statuses {
+		st2 := *st
+		sources = append(sources, &st2)
+	}
+	return s.snap, sources
+}
@@ -0,0 +1,199 @@
+// Copyright 2022 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package worker
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/julieqiu/derrors"
+	"github.com/julieqiu/github/internal/client"
+)
+
+// Rules say how the dashboard sorts issues into sections.
+type Rules struct {
+	// Labels are the names of the labels used to derive the status of an
+	// issue. Empty names are the defaults; see client.DefaultLabelNames.
+	Labels client.LabelNames
+	// Sections are the dashboard sections, in display order. Each issue is
+	// shown in the first section that it matches, if any.
+	Sections []*SectionRule
+}
+
+// A SectionRule describes a dashboard section and the issues it holds. An
+// issue must match every condition that is set.
+type SectionRule struct {
+	Name string
+	// Labels are labels the issue must have, and NotLabels labels it must
+	// not have.
+	Labels    []string
+	NotLabels []string
+	// State is "open" or "closed".
+	State string
+	// Statuses are lifecycle statuses, one of which the issue must have.
+	Statuses []client.Status
+	// StdLib, if set, says whether the issue must be for the standard
+	// library.
+	StdLib *bool
+	// Sort is the order of the issues in the section: one of the keys in
+	// sortKeys, optionally prefixed with "-" to reverse it. The default is
+	// "module".
+	Sort string
+}
+
+// sortKeys are the orders that a SectionRule may use.
+var sortKeys = map[string]func(a, b *client.Issue) bool{
+	"number":  func(a, b *client.Issue) bool { return a.Number < b.Number },
+	"module":  func(a, b *client.Issue) bool { return a.ModulePath < b.ModulePath },
+	"created": func(a, b *client.Issue) bool { return a.CreatedAt.Before(b.CreatedAt) },
+	"updated": func(a, b *client.Issue) bool { return a.UpdatedAt.Before(b.UpdatedAt) },
+	"closed":  func(a, b *client.Issue) bool { return a.ClosedAt.Before(b.ClosedAt) },
+}
+
+// DefaultRules are the rules used when no rules file is given. They have a
+// section for the third-party issues with each status.
+func DefaultRules() *Rules {
+	r := &Rules{Labels: client.DefaultLabelNames}
+	thirdParty := false
+	for _, st := range client.Statuses {
+		r.Sections = append(r.Sections, &SectionRule{
+			Name:     "Third Party: " + string(st),
+			Statuses: []client.Status{st},
+			StdLib:   &thirdParty,
+			Sort:     "module",
+		})
+	}
+	return r
+}
+
+// ReadRules reads Rules from a JSON file, such as
+//
+//	{
+//	  "Labels": {"NotGoVuln": "not-go", "Duplicate": "dup"},
+//	  "Sections": [
+//	    {"Name": "Needs CVE", "Labels": ["NeedsCVEID"], "State": "open", "Sort": "-updated"},
+//	    {"Name": "Third Party", "StdLib": false, "Statuses": ["new", "triaged"]}
+//	  ]
+//	}
+func ReadRules(filename string) (_ *Rules, err error) {
+	defer derrors.Wrap(&err, "ReadRules(%q)", filename)
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	r := &Rules{}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(r); err != nil {
+		return nil, err
+	}
+	if err := r.check(); err != nil {
+		return nil, err
+	}
+	r.Labels = r.Labels.WithDefaults()
+	return r, nil
+}
+
+// check reports the first problem with the rules.
+func (r *Rules) check() error {
+	seen := map[string]bool{}
+	for k, s := range r.Sections {
+		if s.Name == "" {
+			return fmt.Errorf("section %d has no name", k)
+		}
+		if seen[s.Name] {
+			return fmt.Errorf("section %q: duplicate name", s.Name)
+		}
+		seen[s.Name] = true
+		if s.State != "" && s.State != "open" && s.State != "closed" {
+			return fmt.Errorf("section %q: state %q: want open or closed", s.Name, s.State)
+		}
+		for _, st := range s.Statuses {
+			if _, err := client.ParseStatus(string(st)); err != nil {
+				return fmt.Errorf("section %q: %v", s.Name, err)
+			}
+		}
+		if s.Sort != "" && sortKeys[strings.TrimPrefix(s.Sort, "-")] == nil {
+			var keys []string
+			for k := range sortKeys {
+				keys = append(keys, k)
+			}
+			sort.Strings(keys)
+			return fmt.Errorf("section %q: unknown sort %q (want one of %s)", s.Name, s.Sort, strings.Join(keys, ", "))
+		}
+	}
+	return nil
+}
+
+// match reports whether the issue belongs in the section.
+func (s *SectionRule) match(i *client.Issue, names client.LabelNames) bool {
+	for _, l := range s.Labels {
+		if !i.Labels[l] {
+			return false
+		}
+	}
+	for _, l := range s.NotLabels {
+		if i.Labels[l] {
+			return false
+		}
+	}
+	if s.State != "" && (s.State == "open") != i.Open {
+		return false
+	}
+	if s.StdLib != nil && *s.StdLib != i.IsStdLib {
+		return false
+	}
+	if len(s.Statuses) > 0 {
+		st := i.StatusUsing(names)
+		for _, want := range s.Statuses {
+			if st == want {
+				return true
+			}
+		}
+		return false
+	}
+	return true
+}
+
+// A section is a dashboard section and the issues in it.
+type section struct {
+	Name   string
+	Issues []*client.Issue
+}
+
+// categorize sorts the issues into the sections described by the rules.
+// Every section is returned, even if it is empty.
+func (r *Rules) categorize(issues []*client.Issue) []*section {
+	secs := make([]*section, len(r.Sections))
+	for k, s := range r.Sections {
+		secs[k] = &section{Name: s.Name}
+	}
+	for _, i := range issues {
+		for k, s := range r.Sections {
+			if s.match(i, r.Labels) {
+				secs[k].Issues = append(secs[k].Issues, i)
+				break
+			}
+		}
+	}
+	for k, s := range r.Sections {
+		key := s.Sort
+		if key == "" {
+			key = "module"
+		}
+		less := sortKeys[strings.TrimPrefix(key, "-")]
+		if strings.HasPrefix(key, "-") {
+			asc := less
+			less = func(a, b *client.Issue) bool { return asc(b, a) }
+		}
+		is := secs[k].Issues
+		sort.SliceStable(is, func(i, j int) bool { return less(is[i], is[j]) })
+	}
+	return secs
+}
@@ -13,18 +13,18 @@ import (
 	"os"
 	"path/filepath"
 	"sort"
-	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/google/safehtml/template"
 	"github.com/julieqiu/derrors"
 	log "github.com/julieqiu/dlog"
 	"github.com/julieqiu/github/internal/client"
-	"github.com/julieqiu/github/internal/colly"
+	"github.com/julieqiu/github/internal/reconcile"
+	"github.com/julieqiu/github/internal/stats"
+	"github.com/julieqiu/github/internal/triage"
 	"golang.org/x/mod/semver"
-	"golang.org/x/sync/errgroup"
-	vulnc "golang.org/x/vuln/client"
 	"golang.org/x/vuln/osv"
 )
 
@@ -34,20 +34,27 @@ var staticPath = template.TrustedSourceFromConstant("static")
 
 type Server struct {
 	indexTemplate *template.Template
-	gitHubClient  *client.Client
-	dbClient      vulnc.Client
-	collyClient   *colly.Client
+	sources       Sources
+	rules         *Rules
+
+	refreshInterval time.Duration
+	refreshMu       sync.Mutex // held while refreshing
+	// now returns the current time. Tests replace it.
+	now func() time.Time
+
+	mu       sync.Mutex // protects the fields below
+	snap     *snapshot
+	statuses []*SourceStatus
 }
 
-func NewServer(ctx context.Context, githubClient *client.Client, vulndbClient vulnc.Client, collyClient *colly.Client) (_ *Server, err error) {
+// NewServer returns a Server that renders the dashboard from the given
+// sources, with its issues sorted into sections by rules. If rules is nil,
+// DefaultRules are used. The data is refreshed in the background every
+// refreshInterval, which must be positive, until ctx is done.
+func NewServer(ctx context.Context, sources Sources, rules *Rules, refreshInterval time.Duration) (_ *Server, err error) {
 	defer derrors.Wrap(&err, "NewServer")
 
-	s := &Server{
-		gitHubClient: githubClient,
-		dbClient:     vulndbClient,
-		collyClient:  collyClient,
-	}
-	s.indexTemplate, err = parseTemplate(staticPath, template.TrustedSourceFromConstant("index.tmpl"))
+	s, err := newServer(sources, rules, refreshInterval)
 	if err != nil {
 		return nil, err
 	}
@@ -57,6 +64,44 @@ func NewServer(ctx context.Context, githubClient *client.Client, vulndbClient vu
 		http.ServeFile(w, r, filepath.Join(staticPath.String(), "favicon.ico"))
 		return nil
 	})
+	s.handle(ctx, "/draft/", s.draftReport)
+	s.handle(ctx, "/refresh", func(w http.ResponseWriter, r *http.Request) error {
+		if r.Method != http.MethodPost {
+			return &serverError{status: http.StatusMethodNotAllowed, err: fmt.Errorf("%s not allowed", r.Method)}
+		}
+		// Use the server's context, since the refresh outlives the request.
+		go s.refresh(ctx)
+		http.Redirect(w, r, "/", http.StatusSeeOther)
+		return nil
+	})
+	go s.refreshLoop(ctx)
+	return s, nil
+}
+
+// newServer returns a Server with no data, that neither handles requests nor
+// refreshes until told to.
+func newServer(sources Sources, rules *Rules, refreshInterval time.Duration) (*Server, error) {
+	if refreshInterval <= 0 {
+		return nil, fmt.Errorf("refresh interval %s is not positive", refreshInterval)
+	}
+	if rules == nil {
+		rules = DefaultRules()
+	}
+	s := &Server{
+		sources:         sources,
+		rules:           rules,
+		refreshInterval: refreshInterval,
+		now:             time.Now,
+		snap:            &snapshot{},
+	}
+	for _, name := range sourceNames {
+		s.statuses = append(s.statuses, &SourceStatus{Name: name})
+	}
+	var err error
+	s.indexTemplate, err = parseTemplate(staticPath, template.TrustedSourceFromConstant("index.tmpl"), rules.Labels)
+	if err != nil {
+		return nil, err
+	}
 	return s, nil
 }
 
@@ -109,14 +154,19 @@ func translateStatus(code int) int64 {
 	return int64(code)
 }
 
-// Parse a template.
-func parseTemplate(staticPath, filename template.TrustedSource) (*template.Template, error) {
+// Parse a template. Its status and labels functions use the given label
+// names.
+func parseTemplate(staticPath, filename template.TrustedSource, names client.LabelNames) (*template.Template, error) {
 	if staticPath.String() == "" {
 		return nil, nil
 	}
 	templatePath := template.TrustedSourceJoin(staticPath, filename)
 	return template.New(filename.String()).Funcs(template.FuncMap{
-		"timefmt": FormatTime,
+		"timefmt":  FormatTime,
+		"durfmt":   stats.FormatDuration,
+		"statuses": func() []client.Status { return client.Statuses },
+		"status":   func(i *client.Issue) client.Status { return i.StatusUsing(names) },
+		"labels":   func() client.LabelNames { return names },
 	}).ParseFilesFromTrustedSources(templatePath)
 }
 
@@ -159,113 +209,66 @@ type StdlibReport struct {
 }
 
 type indexPage struct {
-	NumIssues         int
-	NumOpen           int
-	NumClosed         int
-	NumDBReports      int
-	StdLibIssues      []*client.Issue
-	OpenIssues        []*client.Issue
-	ClosedNotGoVuln   []*client.Issue
-	ClosedNeedsReport []*client.Issue
-	ClosedDuplicate   []*client.Issue
-	ClosedOther       []*client.Issue
-	DBReports         map[int]*osv.Entry
-	ReleaseNotes      []*StdlibReport
+	NumIssues    int
+	NumOpen      int
+	NumClosed    int
+	NumDBReports int
+	StdLibIssues []*client.Issue
+	// Sections are the issues sorted into sections by the server's rules.
+	Sections        []*section
+	MalformedIssues []*client.MalformedIssue
+	DBReports       map[int]*osv.Entry
+	ReleaseNotes    []*StdlibReport
+	Sources         []*SourceStatus
+	RateLimits      []client.RateLimit
+	Timelines       []*stats.DurationSummary
+	UncoveredGHSAs  []*client.SecurityAdvisory
+	OrphanedIssues  []*reconcile.Orphan
+	BadTransitions  []*statusChange
+	Mismatches      []*reconcile.Mismatch
+	Duplicates      []*triage.DuplicateGroup
+	// Drafts are the numbers of the issues that need a report and have a
+	// known GHSA to draft one from.
+	Drafts map[int]bool
+	// Filter is the filter applied to the issues, and Issues are the
+	// issues that pass it, newest first.
+	Filter issueFilter
+	Issues []*client.Issue
 }
 
 func (s *Server) indexPage(w http.ResponseWriter, r *http.Request) error {
-	g, ctx := errgroup.WithContext(r.Context())
-	dbReports := map[int]string{}
-	g.Go(func() error {
-		ids, err := s.dbClient.ListIDs(ctx)
-		if err != nil {
-			return err
-		}
-		fmt.Println("ListIDs: ", len(ids))
-		for _, id := range ids {
-			parts := strings.Split(id, "-")
-			n, err := strconv.Atoi(parts[2])
-			if err != nil {
-				return err
-			}
-			dbReports[n] = id
-		}
-		fmt.Println("dbReports: ", len(dbReports))
-		return nil
-	})
-	var issues []*client.Issue
-	g.Go(func() error {
-		issues2, err := s.gitHubClient.ListByRepo(r.Context())
-		if err != nil {
-			return err
-		}
-		fmt.Println(len(issues2))
-		issues = issues2
-		return nil
-	})
-	var ghsas []*client.SecurityAdvisory
-	g.Go(func() error {
-		ghsas2, err := s.gitHubClient.ListGHSAs(ctx, time.Time{})
-		ghsas = ghsas2
-		return err
-	})
-	var releaseNotes []*colly.ReleaseNote
-	g.Go(func() error {
-		releaseNotes = s.collyClient.ReleaseNotes()
-		return nil
-	})
-	if err := g.Wait(); err != nil {
-		return err
-	}
-
-	for _, ghsa := range ghsas {
-		for _, id := range ghsa.Identifiers {
-			fmt.Printf("%s: %s, ", id.Type, id.Value)
-		}
-		fmt.Println()
-	}
-	fmt.Println("GHSAs: ", len(ghsas))
-
-	g, ctx = errgroup.WithContext(r.Context())
-	for _, i := range issues {
-		i := i
-		g.Go(func() error {
-			if goid, ok := dbReports[i.Number]; ok {
-				i.HasReport = true
-				osv, err := s.dbClient.GetByID(ctx, goid)
-				if err != nil {
-					return err
-				}
-				i.OSV = osv
-				for _, aff := range osv.Affected {
-					i.PackagePath = aff.Package.Name
-					for _, r := range aff.Ranges {
-						for _, event := range r.Events {
-							if event.Introduced != "" {
-								i.Introduced = append(i.Introduced, event.Introduced)
-							}
-							if event.Fixed != "" {
-								i.Fixed = append(i.Fixed, event.Fixed)
-							}
-						}
-					}
-				}
-			}
-			return nil
-		})
+	snap, sources := s.currentSnapshot()
+	page := &indexPage{
+		NumDBReports:    snap.numDBReports,
+		DBReports:       map[int]*osv.Entry{},
+		Sources:         sources,
+		MalformedIssues: snap.malformed,
+		BadTransitions:  snap.badTransitions,
+		Mismatches:      snap.mismatches,
+		Duplicates:      snap.duplicates,
+		Drafts:          map[int]bool{},
+		Filter:          parseIssueFilter(r.URL.Query()),
 	}
-	if err := g.Wait(); err != nil {
-		return err
+	if s.sources.RateLimits != nil {
+		page.RateLimits = s.sources.RateLimits.RateLimits()
 	}
-
-	page := &indexPage{
-		NumDBReports: len(dbReports),
-		DBReports:    map[int]*osv.Entry{},
+	rec := reconcile.Reconcile(snap.issues, snap.ghsas, snap.reports)
+	page.UncoveredGHSAs = rec.Uncovered
+	page.OrphanedIssues = rec.Orphaned
+	var issues []*client.Issue
+	for _, i := range snap.issues {
+		if page.Filter.match(i, s.rules.Labels) {
+			issues = append(issues, i)
+		}
 	}
-	fmt.Println(page.NumDBReports)
+	page.Timelines = stats.TimelineSummaries(issues, s.rules.Labels)
+	page.Issues = append([]*client.Issue(nil), issues...)
+	sort.Slice(page.Issues, func(i, j int) bool {
+		return page.Issues[i].Number > page.Issues[j].Number
+	})
 
 	releaseNotes2 := map[string]*StdlibReport{}
-	for _, r := range releaseNotes {
+	for _, r := range snap.releaseNotes {
 		r2 := &StdlibReport{
 			Version:     strings.TrimPrefix(r.Version, "go"),
 			Description: r.Description,
@@ -274,6 +277,9 @@ func (s *Server) indexPage(w http.ResponseWriter, r *http.Request) error {
 	}
 	for _, i := range issues {
 		page.DBReports[i.Number] = i.OSV
+		if i.Labels[s.rules.Labels.NeedsReport] && !i.HasReport && snap.issueAdvisory(i) != nil {
+			page.Drafts[i.Number] = true
+		}
 		page.NumIssues += 1
 		if i.Open {
 			page.NumOpen += 1
@@ -282,27 +288,14 @@ func (s *Server) indexPage(w http.ResponseWriter, r *http.Request) error {
 		}
 		if i.IsStdLib {
 			page.StdLibIssues = append(page.StdLibIssues, i)
-			for _, f := range i.Fixed {
+			for _, f := range i.FixedVersions() {
 				if r2, ok := releaseNotes2[f]; ok {
 					r2.Issues = append(r2.Issues, i)
 				}
 			}
-			continue
-		}
-		if i.Open {
-			page.OpenIssues = append(page.OpenIssues, i)
-		} else {
-			if i.Labels["NotGoVuln"] {
-				page.ClosedNotGoVuln = append(page.ClosedNotGoVuln, i)
-			} else if i.Labels["NeedsReport"] {
-				page.ClosedNeedsReport = append(page.ClosedNeedsReport, i)
-			} else if i.Labels["duplicate"] {
-				page.ClosedDuplicate = append(page.ClosedDuplicate, i)
-			} else {
-				page.ClosedOther = append(page.ClosedDuplicate, i)
-			}
 		}
 	}
+	page.Sections = s.rules.categorize(issues)
 	for _, r2 := range releaseNotes2 {
 		page.ReleaseNotes = append(page.ReleaseNotes, r2)
 	}
@@ -311,16 +304,7 @@ func (s *Server) indexPage(w http.ResponseWriter, r *http.Request) error {
 		return semver.Compare("v"+page.ReleaseNotes[i].Version, "v"+page.ReleaseNotes[j].Version) > 0
 	})
 	sort.Slice(page.StdLibIssues, func(i, j int) bool {
-		return page.StdLibIssues[i].PackagePath < page.StdLibIssues[j].PackagePath
-	})
-	sort.Slice(page.OpenIssues, func(i, j int) bool {
-		return page.OpenIssues[i].ModulePath < page.OpenIssues[j].ModulePath
-	})
-	sort.Slice(page.ClosedNeedsReport, func(i, j int) bool {
-		return page.ClosedNeedsReport[i].ModulePath < page.ClosedNeedsReport[j].ModulePath
+		return strings.Join(page.StdLibIssues[i].Packages(), " ") < strings.Join(page.StdLibIssues[j].Packages(), " ")
 	})
-	// sort.Slice(page.ClosedIssues, func(i, j int) bool {
-	// return page.ClosedIssues[i].ModulePath < page.ClosedIssues[j].ModulePath
-	// })
 	return renderPage(r.Context(), w, page, s.indexTemplate)
 }
@@ -0,0 +1,133 @@
+// Copyright 2022 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package worker
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"flag"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/google/safehtml/template"
+	"github.com/julieqiu/github/internal/client"
+)
+
+var update = flag.Bool("update", false, "update the golden files in testdata")
+
+// failingAdvisories is an AdvisorySource that returns err if it is set.
+type failingAdvisories struct {
+	AdvisorySource
+	err error
+}
+
+func (f *failingAdvisories) ListGHSAs(ctx context.Context) ([]*client.SecurityAdvisory, error) {
+	if f.err != nil {
+		return nil, f.err
+	}
+	return f.AdvisorySource.ListGHSAs(ctx)
+}
+
+// newTestServer returns a Server for the data in testdata/memory.json, with
+// its clock stopped, and its AdvisorySource.
+func newTestServer(t *testing.T) (*Server, *failingAdvisories) {
+	t.Helper()
+	staticPath = template.TrustedSourceFromConstant("../../static")
+	m, err := ReadMemorySource("testdata/memory.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	sources := m.Sources()
+	ghsas := &failingAdvisories{AdvisorySource: sources.Advisories}
+	sources.Advisories = ghsas
+	s, err := newServer(sources, nil, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.now = func() time.Time { return time.Date(2022, 10, 1, 12, 0, 0, 0, time.UTC) }
+	return s, ghsas
+}
+
+// get serves a GET of target with handler, and returns the response.
+func get(t *testing.T, handler func(http.ResponseWriter, *http.Request) error, target string) *httptest.ResponseRecorder {
+	t.Helper()
+	w := httptest.NewRecorder()
+	if err := handler(w, httptest.NewRequest(http.MethodGet, target, nil)); err != nil {
+		t.Fatalf("GET %s: %v", target, err)
+	}
+	return w
+}
+
+// checkGolden compares got with the contents of testdata/name, or writes
+// them there if the -update flag is set.
+func checkGolden(t *testing.T, name string, got []byte) {
+	t.Helper()
+	filename := filepath.Join("testdata", name)
+	if *update {
+		if err := os.WriteFile(filename, got, 0644); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+	want, err := os.ReadFile(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Errorf("%s differs; run go test -update and check the diff:\n%s", name, got)
+	}
+}
+
+func TestIndexPage(t *testing.T) {
+	s, ghsas := newTestServer(t)
+	ctx := context.Background()
+	s.refresh(ctx)
+	checkGolden(t, "index.golden", get(t, s.indexPage, "/").Body.Bytes())
+
+	// A source that fails keeps the data of the previous refresh, and the
+	// page shows the error.
+	ghsas.err = errors.New("GitHub is down")
+	s.now = func() time.Time { return time.Date(2022, 10, 1, 13, 0, 0, 0, time.UTC) }
+	s.refresh(ctx)
+	checkGolden(t, "index_error.golden", get(t, s.indexPage, "/").Body.Bytes())
+	if snap, _ := s.currentSnapshot(); len(snap.ghsas) != 3 {
+		t.Errorf("after a failed refresh, got %d GHSAs, want the 3 from before", len(snap.ghsas))
+	}
+}
+
+func TestDraftReport(t *testing.T) {
+	s, _ := newTestServer(t)
+	s.refresh(context.Background())
+
+	w := get(t, s.draftReport, "/draft/141.yaml")
+	if got, want := w.Header().Get("Content-Disposition"), "attachment; filename=GO-2022-0141.yaml"; got != want {
+		t.Errorf("Content-Disposition = %q, want %q", got, want)
+	}
+	checkGolden(t, "draft141.golden", w.Body.Bytes())
+
+	// Issue 146 only has a CVE, which a GHSA lists.
+	checkGolden(t, "draft146.golden", get(t, s.draftReport, "/draft/146.yaml").Body.Bytes())
+
+	for _, target := range []string{"/draft/143.yaml", "/draft/999.yaml", "/draft/141"} {
+		err := s.draftReport(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, target, nil))
+		var serr *serverError
+		if !errors.As(err, &serr) || serr.status != http.StatusNotFound {
+			t.Errorf("GET %s: got error %v, want 404", target, err)
+		}
+	}
+}
+
+func TestNewServerRefreshInterval(t *testing.T) {
+	for _, d := range []time.Duration{0, -time.Minute} {
+		if _, err := newServer(Sources{}, nil, d); err == nil {
+			t.Errorf("newServer accepted a refresh interval of %s", d)
+		}
+	}
+}
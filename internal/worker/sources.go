@@ -0,0 +1,119 @@
+// Copyright 2022 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package worker
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+
+	"github.com/julieqiu/derrors"
+	"github.com/julieqiu/github/internal/client"
+	"github.com/julieqiu/github/internal/colly"
+	"golang.org/x/vuln/osv"
+)
+
+// An IssueSource provides the vulndb issues.
+type IssueSource interface {
+	// ListIssues returns all issues, and separately those that could not
+	// be parsed. The caller must not modify them.
+	ListIssues(ctx context.Context) ([]*client.Issue, []*client.MalformedIssue, error)
+}
+
+// An AdvisorySource provides the GitHub security advisories that affect Go.
+type AdvisorySource interface {
+	// ListGHSAs returns all advisories.
+	ListGHSAs(ctx context.Context) ([]*client.SecurityAdvisory, error)
+}
+
+// A ReportSource provides the reports published in the vulndb.
+type ReportSource interface {
+	// ListReports returns all published reports.
+	ListReports(ctx context.Context) ([]*osv.Entry, error)
+}
+
+// A ReleaseNotesSource provides the Go release notes.
+// It is implemented by *colly.Client.
+type ReleaseNotesSource interface {
+	ReleaseNotes() ([]*colly.ReleaseNote, error)
+}
+
+// A RateLimitSource reports the remaining GitHub API budget.
+// It is implemented by *client.Client.
+type RateLimitSource interface {
+	RateLimits() []client.RateLimit
+}
+
+// Sources are where a Server gets the data for the dashboard.
+type Sources struct {
+	Issues       IssueSource
+	Advisories   AdvisorySource
+	Reports      ReportSource
+	ReleaseNotes ReleaseNotesSource
+	// RateLimits is optional.
+	RateLimits RateLimitSource
+}
+
+// A MemorySource is an in-memory implementation of IssueSource,
+// AdvisorySource, ReportSource and ReleaseNotesSource that always returns
+// its fields.
+type MemorySource struct {
+	Issues       []*client.Issue
+	Malformed    []*client.MalformedIssue
+	GHSAs        []*client.SecurityAdvisory
+	Reports      []*osv.Entry
+	ReleaseNotes []*colly.ReleaseNote
+}
+
+// ReadMemorySource returns a MemorySource populated from a JSON file holding
+// a MemorySource.
+func ReadMemorySource(filename string) (_ *MemorySource, err error) {
+	defer derrors.Wrap(&err, "ReadMemorySource(%q)", filename)
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	var m MemorySource
+	if err := json.Unmarshal(data, &m); err != nil {
+		return nil, err
+	}
+	return &m, nil
+}
+
+// Sources returns Sources that are all served by m.
+func (m *MemorySource) Sources() Sources {
+	return Sources{
+		Issues:       m,
+		Advisories:   m,
+		Reports:      m,
+		ReleaseNotes: memoryReleaseNotes{m},
+	}
+}
+
+// ListIssues implements IssueSource.
+func (m *MemorySource) ListIssues(context.Context) ([]*client.Issue, []*client.MalformedIssue, error) {
+	return m.Issues, m.Malformed, nil
+}
+
+// ListGHSAs implements AdvisorySource.
+func (m *MemorySource) ListGHSAs(context.Context) ([]*client.SecurityAdvisory, error) {
+	return m.GHSAs, nil
+}
+
+// ListReports implements ReportSource.
+func (m *MemorySource) ListReports(context.Context) ([]*osv.Entry, error) {
+	return m.Reports, nil
+}
+
+// memoryReleaseNotes adapts a MemorySource to ReleaseNotesSource; the
+// method cannot be defined on MemorySource because of the field of the same
+// name.
+type memoryReleaseNotes struct {
+	m *MemorySource
+}
+
+func (r memoryReleaseNotes) ReleaseNotes() ([]*colly.ReleaseNote, error) {
+	return r.m.ReleaseNotes, nil
+}